@@ -1,27 +1,56 @@
 package ovirt
 
 import (
+	"bufio"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
 	"net/url"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/packer/template/interpolate"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
 )
 
 // AccessConfig contains the oVirt API access and authentication configuration
 type AccessConfig struct {
+	Profile            string `mapstructure:"profile"`
 	OvirtURLRaw        string `mapstructure:"ovirt_url"`
 	OvirtURL           *url.URL
 	SkipCertValidation bool   `mapstructure:"insecure_skip_tls_verify"`
+	AllowHTTP          bool   `mapstructure:"insecure_allow_http"`
 	Username           string `mapstructure:"username"`
 	Password           string `mapstructure:"password"`
+	CAFile             string `mapstructure:"ca_file"`
+	CACert             string `mapstructure:"ca_cert"`
+
+	// caFilePath is the CA bundle passed to the SDK which only reads
+	// certificates from files
+	caFilePath string
+	caPool     *x509.CertPool
 }
 
 // Prepare performs basic validation on the AccessConfig
 func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 	var errs []error
 
+	if c.Profile == "" {
+		c.Profile = os.Getenv("OVIRT_PROFILE")
+	}
+	if c.Profile != "" {
+		if err := c.loadProfile(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	if c.OvirtURLRaw == "" {
 		c.OvirtURLRaw = os.Getenv("OVIRT_URL")
 	}
@@ -31,6 +60,9 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 	if c.Password == "" {
 		c.Password = os.Getenv("OVIRT_PASSWORD")
 	}
+	if c.CAFile == "" {
+		c.CAFile = os.Getenv("OVIRT_CAFILE")
+	}
 
 	// Required configurations that will display errors if not set
 	if c.Username == "" {
@@ -41,11 +73,19 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 	}
 	if c.OvirtURLRaw == "" {
 		errs = append(errs, errors.New("ovirt_url must be specified"))
+	} else if err := c.prepareURL(); err != nil {
+		errs = append(errs, err)
 	}
 
-	var err error
-	if c.OvirtURL, err = url.Parse(c.OvirtURLRaw); err != nil {
-		errs = append(errs, fmt.Errorf("Could not parse ovirt_url: %s", err))
+	if (c.CAFile != "") && (c.CACert != "") {
+		errs = append(errs, errors.New("Conflict: Set either ca_file or ca_cert"))
+	} else if (c.CAFile != "") || (c.CACert != "") {
+		if c.SkipCertValidation {
+			errs = append(errs, errors.New("Conflict: ca_file and ca_cert can't be used together with insecure_skip_tls_verify"))
+		}
+		if err := c.prepareCA(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
 	if len(errs) > 0 {
@@ -54,3 +94,234 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 
 	return nil
 }
+
+// prepareURL validates ovirt_url and sets OvirtURL. The scheme defaults to
+// https and the API path is appended if only a host is given.
+func (c *AccessConfig) prepareURL() error {
+	raw := c.OvirtURLRaw
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("Could not parse ovirt_url: %s", err)
+	}
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if !c.AllowHTTP {
+			return errors.New("ovirt_url must use https unless insecure_allow_http is set")
+		}
+	default:
+		return fmt.Errorf("Invalid scheme of ovirt_url: %s", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("ovirt_url must contain a host: %s", c.OvirtURLRaw)
+	}
+	if (u.Path == "") || (u.Path == "/") {
+		u.Path = "/ovirt-engine/api"
+		log.Printf("Using ovirt_url: %s", u.String())
+	}
+
+	c.OvirtURL = u
+	return nil
+}
+
+// profilePath returns the location of the file containing the connection
+// profiles.
+func profilePath() (string, error) {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		if runtime.GOOS == "windows" {
+			configDir = os.Getenv("APPDATA")
+		} else {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			configDir = filepath.Join(home, ".config")
+		}
+	}
+	return filepath.Join(configDir, "ovirt", "ovirt.conf"), nil
+}
+
+// loadProfile sets the settings from the configured connection profile
+// which aren't given explicitly.
+func (c *AccessConfig) loadProfile() error {
+	path, err := profilePath()
+	if err != nil {
+		return fmt.Errorf("Could not determine profile location: %s", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Could not read profile: %s", err)
+	}
+	defer f.Close()
+
+	settings, err := readProfile(f, c.Profile)
+	if err != nil {
+		return fmt.Errorf("Could not read profile from '%s': %s", path, err)
+	}
+	log.Printf("Using profile '%s' from: %s", c.Profile, path)
+
+	// Credentials and the certificate validation are taken as a whole, so
+	// that the profile doesn't override or contradict explicit settings
+	explicitCredentials := (c.Username != "") || (c.Password != "")
+	explicitCA := (c.CAFile != "") || (c.CACert != "") || c.SkipCertValidation
+
+	for key, value := range settings {
+		switch key {
+		case "url":
+			if c.OvirtURLRaw == "" {
+				c.OvirtURLRaw = value
+			}
+		case "username":
+			if !explicitCredentials {
+				c.Username = value
+			}
+		case "password":
+			if !explicitCredentials {
+				c.Password = value
+			}
+		case "ca_file":
+			if !explicitCA {
+				c.CAFile = value
+			}
+		case "insecure":
+			insecure, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("Invalid insecure value in profile '%s': %s", c.Profile, value)
+			}
+			if !explicitCA {
+				c.SkipCertValidation = insecure
+			}
+		default:
+			return fmt.Errorf("Unknown setting in profile '%s': %s", c.Profile, key)
+		}
+	}
+
+	return nil
+}
+
+// readProfile parses the INI formatted profiles from r and returns the
+// settings of the section with the given name.
+func readProfile(r io.Reader, name string) (map[string]string, error) {
+	var settings map[string]string
+	var section string
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if (line == "") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if (section == name) && (settings == nil) {
+				settings = make(map[string]string)
+			}
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid line %d: %s", n, line)
+		}
+		if section == name {
+			key := strings.TrimSpace(line[:i])
+			settings[key] = strings.Trim(strings.TrimSpace(line[i+1:]), `"`)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if settings == nil {
+		return nil, fmt.Errorf("profile '%s' not found", name)
+	}
+	return settings, nil
+}
+
+// prepareCA loads the configured CA certificates. Certificates given inline
+// are only written to a file when connecting.
+func (c *AccessConfig) prepareCA() error {
+	pem := []byte(c.CACert)
+	if c.CAFile != "" {
+		var err error
+		if pem, err = ioutil.ReadFile(c.CAFile); err != nil {
+			return fmt.Errorf("Could not read ca_file: %s", err)
+		}
+	}
+
+	c.caPool = x509.NewCertPool()
+	if !c.caPool.AppendCertsFromPEM(pem) {
+		return errors.New("Could not find any PEM encoded certificate in CA")
+	}
+
+	if c.CAFile != "" {
+		c.caFilePath = c.CAFile
+	}
+	return nil
+}
+
+// writeCACert stores ca_cert in a temporary file, as the SDK only reads
+// certificates from files. The file is removed by removeCACert.
+func (c *AccessConfig) writeCACert() error {
+	f, err := ioutil.TempFile("", "packer-ovirt-ca-*.pem")
+	if err != nil {
+		return fmt.Errorf("Could not create file for ca_cert: %s", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(c.CACert); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("Could not write ca_cert to '%s': %s", f.Name(), err)
+	}
+	c.caFilePath = f.Name()
+	return nil
+}
+
+// removeCACert removes the temporary file written for ca_cert.
+func (c *AccessConfig) removeCACert() {
+	if (c.CACert == "") || (c.caFilePath == "") {
+		return
+	}
+	if err := os.Remove(c.caFilePath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error removing '%s': %s", c.caFilePath, err)
+	}
+	c.caFilePath = ""
+}
+
+// connect establishes a new connection to the oVirt API
+func (c *AccessConfig) connect() (*ovirtsdk4.Connection, error) {
+	connBuilder := ovirtsdk4.NewConnectionBuilder().
+		URL(c.OvirtURL.String()).
+		Username(c.Username).
+		Password(c.Password).
+		Insecure(c.SkipCertValidation).
+		Compress(true).
+		Timeout(time.Second * 10)
+	if (c.CACert != "") && (c.caFilePath == "") {
+		if err := c.writeCACert(); err != nil {
+			return nil, err
+		}
+	}
+	if c.caFilePath != "" {
+		connBuilder.CAFile(c.caFilePath)
+	}
+	conn, err := connBuilder.Build()
+	if err != nil {
+		c.removeCACert()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// disconnect closes a connection returned by connect and removes the
+// temporary files needed for it.
+func (c *AccessConfig) disconnect(conn *ovirtsdk4.Connection) {
+	if err := conn.Close(); err != nil {
+		log.Printf("Error closing connection: %s", err)
+	}
+	c.removeCACert()
+}
@@ -1,7 +1,19 @@
 package ovirt
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAccessConfig_Prepare(t *testing.T) {
@@ -47,8 +59,254 @@ func TestAccessConfig_Prepare(t *testing.T) {
 	}
 }
 
+func TestAccessConfig_PrepareProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-ovirt")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "ovirt"), 0755); err != nil {
+		t.Fatalf("failed to create config directory: %s", err)
+	}
+	profiles := `# oVirt engines
+[lab]
+url = https://lab.example.com/ovirt-engine/api
+username = admin@internal
+password = "lab-password"
+insecure = true
+
+[prod]
+url = https://prod.example.com/ovirt-engine/api
+username = packer@internal
+password = prod-password
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "ovirt", "ovirt.conf"), []byte(profiles), 0600); err != nil {
+		t.Fatalf("failed to write profile: %s", err)
+	}
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+
+	ac := AccessConfig{Profile: "lab"}
+	if errs := ac.Prepare(nil); errs != nil {
+		t.Fatalf("should accept profile: %s", errs)
+	}
+	if (ac.OvirtURLRaw != "https://lab.example.com/ovirt-engine/api") || (ac.Username != "admin@internal") || (ac.Password != "lab-password") || !ac.SkipCertValidation {
+		t.Fatalf("should load settings from profile: %+v", ac)
+	}
+
+	ac = AccessConfig{Profile: "lab", Username: "packer@internal", Password: "password"}
+	if errs := ac.Prepare(nil); errs != nil {
+		t.Fatalf("should accept profile with explicit settings: %s", errs)
+	}
+	if (ac.Username != "packer@internal") || (ac.Password != "password") {
+		t.Fatalf("should prefer explicit settings over profile: %+v", ac)
+	}
+
+	ac = AccessConfig{Profile: "prod", Username: "admin@internal"}
+	if errs := ac.Prepare(nil); errs == nil {
+		t.Fatal("should not combine explicit username with profile password")
+	}
+	if ac.Password != "" {
+		t.Fatalf("should not use profile password with explicit username: %s", ac.Password)
+	}
+
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(caFile, []byte(testCACert(t)), 0600); err != nil {
+		t.Fatalf("failed to write CA file: %s", err)
+	}
+	ac = AccessConfig{Profile: "lab", CAFile: caFile}
+	if errs := ac.Prepare(nil); errs != nil {
+		t.Fatalf("should accept profile insecure with explicit ca_file: %s", errs)
+	}
+	if ac.SkipCertValidation {
+		t.Fatal("should not disable cert validation with explicit ca_file")
+	}
+
+	os.Setenv("OVIRT_PROFILE", "prod")
+	ac = AccessConfig{}
+	errs := ac.Prepare(nil)
+	os.Unsetenv("OVIRT_PROFILE")
+	if errs != nil {
+		t.Fatalf("should accept OVIRT_PROFILE: %s", errs)
+	}
+	if (ac.OvirtURLRaw != "https://prod.example.com/ovirt-engine/api") || (ac.Username != "packer@internal") {
+		t.Fatalf("should load profile from environment: %+v", ac)
+	}
+
+	ac = AccessConfig{Profile: "staging"}
+	if errs := ac.Prepare(nil); errs == nil {
+		t.Fatal("should not accept unknown profile")
+	}
+}
+
+func TestReadProfile(t *testing.T) {
+	settings, err := readProfile(strings.NewReader("[a]\nurl = https://a\n; comment\n[b]\nurl=https://b\n"), "b")
+	if err != nil {
+		t.Fatalf("should read profile: %s", err)
+	}
+	if (len(settings) != 1) || (settings["url"] != "https://b") {
+		t.Fatalf("should only return settings of profile: %v", settings)
+	}
+
+	if _, err := readProfile(strings.NewReader("[a]\nurl\n"), "a"); err == nil {
+		t.Fatal("should not accept invalid line")
+	}
+
+	if _, err := readProfile(strings.NewReader("[a]\nurl = https://a\n"), "b"); err == nil {
+		t.Fatal("should fail for missing profile")
+	}
+}
+
+func TestAccessConfig_PrepareURL(t *testing.T) {
+	cases := []struct {
+		raw       string
+		allowHTTP bool
+		expected  string
+		fail      bool
+	}{
+		{raw: "https://ovirt.example.com/ovirt-engine/api", expected: "https://ovirt.example.com/ovirt-engine/api"},
+		{raw: "https://ovirt.example.com:8443/ovirt/api", expected: "https://ovirt.example.com:8443/ovirt/api"},
+		{raw: "https://ovirt.example.com", expected: "https://ovirt.example.com/ovirt-engine/api"},
+		{raw: "https://ovirt.example.com/", expected: "https://ovirt.example.com/ovirt-engine/api"},
+		{raw: "ovirt.example.com", expected: "https://ovirt.example.com/ovirt-engine/api"},
+		{raw: "ovirt.example.com:8443", expected: "https://ovirt.example.com:8443/ovirt-engine/api"},
+		{raw: "http://ovirt.example.com", fail: true},
+		{raw: "http://ovirt.example.com", allowHTTP: true, expected: "http://ovirt.example.com/ovirt-engine/api"},
+		{raw: "ftp://ovirt.example.com", fail: true},
+		{raw: "https://", fail: true},
+		{raw: "https:///ovirt-engine/api", fail: true},
+		{raw: ":foobar", fail: true},
+	}
+
+	for _, tc := range cases {
+		ac := testAccessConfig()
+		ac.OvirtURLRaw = tc.raw
+		ac.AllowHTTP = tc.allowHTTP
+		errs := ac.Prepare(nil)
+		if tc.fail {
+			if errs == nil {
+				t.Errorf("should not accept ovirt_url %q", tc.raw)
+			}
+			continue
+		}
+		if errs != nil {
+			t.Errorf("should accept ovirt_url %q: %v", tc.raw, errs)
+			continue
+		}
+		if ac.OvirtURL.String() != tc.expected {
+			t.Errorf("ovirt_url %q: expected %q, got %q", tc.raw, tc.expected, ac.OvirtURL.String())
+		}
+	}
+}
+
+func TestAccessConfig_PrepareCA(t *testing.T) {
+	caCert := testCACert(t)
+
+	dir, err := ioutil.TempDir("", "packer-ovirt")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(caFile, []byte(caCert), 0600); err != nil {
+		t.Fatalf("failed to write CA file: %s", err)
+	}
+
+	ac := testAccessConfig()
+	ac.CAFile = caFile
+	if errs := ac.Prepare(nil); errs != nil {
+		t.Fatalf("should accept ca_file: %s", errs)
+	}
+	if (ac.caFilePath != caFile) || (ac.caPool == nil) {
+		t.Fatalf("should use ca_file: %s", ac.caFilePath)
+	}
+
+	ac = testAccessConfig()
+	ac.CACert = caCert
+	if errs := ac.Prepare(nil); errs != nil {
+		t.Fatalf("should accept ca_cert: %s", errs)
+	}
+	if ac.caFilePath != "" {
+		t.Fatalf("should not write ca_cert to file when preparing: %s", ac.caFilePath)
+	}
+	if err := ac.writeCACert(); err != nil {
+		t.Fatalf("should write ca_cert to file: %s", err)
+	}
+	caCertFile := ac.caFilePath
+	defer os.Remove(caCertFile)
+	content, err := ioutil.ReadFile(caCertFile)
+	if err != nil {
+		t.Fatalf("should write ca_cert to file: %s", err)
+	}
+	if string(content) != caCert {
+		t.Fatal("should write ca_cert content to file")
+	}
+	ac.removeCACert()
+	if _, err := os.Stat(caCertFile); !os.IsNotExist(err) {
+		t.Fatalf("should remove ca_cert file: %s", caCertFile)
+	}
+
+	os.Setenv("OVIRT_CAFILE", caFile)
+	ac = testAccessConfig()
+	errs := ac.Prepare(nil)
+	os.Unsetenv("OVIRT_CAFILE")
+	if errs != nil {
+		t.Fatalf("should accept OVIRT_CAFILE: %s", errs)
+	}
+	if ac.CAFile != caFile {
+		t.Fatalf("should read ca_file from environment: %s", ac.CAFile)
+	}
+
+	ac = testAccessConfig()
+	ac.CAFile = caFile
+	ac.CACert = caCert
+	if errs := ac.Prepare(nil); errs == nil {
+		t.Fatal("should not accept ca_file together with ca_cert")
+	}
+
+	ac = testAccessConfig()
+	ac.CAFile = caFile
+	ac.SkipCertValidation = true
+	if errs := ac.Prepare(nil); errs == nil {
+		t.Fatal("should not accept ca_file together with insecure_skip_tls_verify")
+	}
+
+	ac = testAccessConfig()
+	ac.CAFile = filepath.Join(dir, "missing.pem")
+	if errs := ac.Prepare(nil); errs == nil {
+		t.Fatal("should not accept missing ca_file")
+	}
+
+	ac = testAccessConfig()
+	ac.CACert = "not a certificate"
+	if errs := ac.Prepare(nil); errs == nil {
+		t.Fatal("should not accept invalid ca_cert")
+	}
+}
+
+// testCACert returns a PEM encoded self-signed certificate
+func testCACert(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ovirt.example.com"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
 func testAccessConfig() AccessConfig {
-	return AccessConfig {
+	return AccessConfig{
 		OvirtURLRaw: "https://ovirt.example.com/ovirt/api",
 		Username:    "admin@internal",
 		Password:    "password",
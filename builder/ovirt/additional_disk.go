@@ -0,0 +1,52 @@
+package ovirt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AdditionalDisk describes a data disk which is created and attached to the
+// VM before it is started.
+type AdditionalDisk struct {
+	Name          string `mapstructure:"name"`
+	Size          int    `mapstructure:"size"`
+	Interface     string `mapstructure:"interface"`
+	Format        string `mapstructure:"format"`
+	StorageDomain string `mapstructure:"storage_domain"`
+}
+
+// Prepare validates the disk settings and fills in the defaults. The name
+// and storage domain are inherited from the main disk if not set.
+func (d *AdditionalDisk) Prepare(defaultName string, defaultStorageDomain string) []error {
+	var errs []error
+
+	if d.Name == "" {
+		d.Name = defaultName
+	}
+	if d.Size < 1 {
+		errs = append(errs, fmt.Errorf("size of additional disk '%s' must be specified", d.Name))
+	}
+	if d.Interface == "" {
+		d.Interface = "virtio"
+	}
+	d.Interface = strings.ToLower(d.Interface)
+	if (d.Interface != "virtio") && (d.Interface != "virtio_scsi") {
+		errs = append(errs, fmt.Errorf("Invalid interface of additional disk '%s': %s", d.Name, d.Interface))
+	}
+	if d.Format == "" {
+		d.Format = "cow"
+	}
+	d.Format = strings.ToLower(d.Format)
+	if (d.Format != "cow") && (d.Format != "raw") {
+		errs = append(errs, fmt.Errorf("Invalid format of additional disk '%s': %s", d.Name, d.Format))
+	}
+	if d.StorageDomain == "" {
+		d.StorageDomain = defaultStorageDomain
+	}
+	if d.StorageDomain == "" {
+		errs = append(errs, errors.New("storage_domain must be specified for additional disks"))
+	}
+
+	return errs
+}
@@ -0,0 +1,83 @@
+package ovirt
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// testAPI is a minimal fake of the oVirt REST API. Requests without a
+// registered handler are answered with a not found fault.
+type testAPI struct {
+	*httptest.Server
+	handlers map[string]http.HandlerFunc
+}
+
+func newTestAPI() *testAPI {
+	api := &testAPI{
+		handlers: make(map[string]http.HandlerFunc),
+	}
+	api.Server = httptest.NewServer(api)
+	return api
+}
+
+// handle registers a handler for a method and a path relative to the API
+// root.
+func (a *testAPI) handle(method string, path string, f http.HandlerFunc) {
+	a.handlers[method+" "+path] = f
+}
+
+// handleXML answers GET requests of a path with a static XML document.
+func (a *testAPI) handleXML(path string, body string) {
+	a.handle("GET", path, func(w http.ResponseWriter, r *http.Request) {
+		writeXML(w, body)
+	})
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/ovirt-engine/sso/oauth/token":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "test-token"}`)
+		return
+	case "/ovirt-engine/services/sso-logout":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{}`)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/ovirt-engine/api")
+	if f, ok := a.handlers[r.Method+" "+path]; ok {
+		f(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprint(w, `<fault><reason>Not Found</reason><detail>Entity not found.</detail></fault>`)
+}
+
+// url returns the URL of the API root.
+func (a *testAPI) url() string {
+	return a.URL + "/ovirt-engine/api"
+}
+
+func (a *testAPI) connect(t *testing.T) *ovirtsdk4.Connection {
+	conn, err := ovirtsdk4.NewConnectionBuilder().
+		URL(a.url()).
+		Username("admin@internal").
+		Password("password").
+		Build()
+	if err != nil {
+		t.Fatalf("should connect to test API: %s", err)
+	}
+	return conn
+}
+
+func writeXML(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/xml")
+	fmt.Fprint(w, body)
+}
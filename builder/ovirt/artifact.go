@@ -1,13 +1,34 @@
 package ovirt
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"strings"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
 )
 
-// Artifact is an artifact implementation that contains built disk.
+// Artifact is an artifact implementation that contains a built disk or
+// template.
 type Artifact struct {
-	diskID string
+	diskID     string
+	templateID string
+	files      []string
+
+	// diskIDs contains the boot disk and all additional disks
+	diskIDs []string
+
+	// stateData contains details about the built image which are exposed
+	// through State()
+	stateData map[string]interface{}
+
+	// connect returns a new connection to the oVirt API. It is required to
+	// destroy the artifact after the build connection was closed.
+	connect func() (*ovirtsdk4.Connection, error)
+	// disconnect closes a connection returned by connect
+	disconnect func(*ovirtsdk4.Connection)
 }
 
 // BuilderId uniquely identifies the builder.
@@ -15,28 +36,126 @@ func (*Artifact) BuilderId() string {
 	return BuilderID
 }
 
-// Files returns the files represented by the artifact. Not used for oVirt.
-func (*Artifact) Files() []string {
-	return nil
+// Files returns the files represented by the artifact. This is only set if
+// the disk was downloaded to the local host.
+func (a *Artifact) Files() []string {
+	return a.files
 }
 
-// Id returns the disk identifier of the artifact.
+// Id returns the template identifier of the artifact or the disk identifier
+// if no template was created.
 func (a *Artifact) Id() string {
+	if a.templateID != "" {
+		return a.templateID
+	}
 	return a.diskID
 }
 
 func (a *Artifact) String() string {
+	if a.templateID != "" {
+		return fmt.Sprintf("A template was created: %s", a.templateID)
+	}
+	if len(a.diskIDs) > 1 {
+		return fmt.Sprintf("Disks were created: %s", strings.Join(a.diskIDs, ", "))
+	}
 	return fmt.Sprintf("A disk was created: %s", a.diskID)
 }
 
-// State returns specific details from the artifact. Not used for oVirt.
+// State returns specific details from the artifact.
 func (a *Artifact) State(name string) interface{} {
-	return nil
+	if name == "atlas.artifact.metadata" {
+		return a.metadata()
+	}
+	return a.stateData[name]
 }
 
-// Destroy deletes the custom image associated with the artifact.
+// metadata returns all artifact details as string map
+func (a *Artifact) metadata() map[string]string {
+	metadata := make(map[string]string)
+	for k, v := range a.stateData {
+		metadata[k] = fmt.Sprintf("%v", v)
+	}
+	return metadata
+}
+
+// Destroy deletes the template or disk associated with the artifact and any
+// downloaded files.
 func (a *Artifact) Destroy() error {
-	log.Printf("Destroying disk: %s", a.diskID)
-	//TODO
+	for _, f := range a.files {
+		log.Printf("Deleting file: %s", f)
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Error deleting file '%s': %s", f, err)
+		}
+	}
+
+	if (a.templateID == "") && (a.diskID == "") {
+		return nil
+	}
+	if a.connect == nil {
+		return errors.New("No oVirt connection available to destroy artifact")
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		return fmt.Errorf("oVirt: Connection failed, reason: %s", err.Error())
+	}
+	if a.disconnect != nil {
+		defer a.disconnect(conn)
+	} else {
+		defer conn.Close()
+	}
+
+	if a.templateID != "" {
+		log.Printf("Destroying template: %s", a.templateID)
+		_, err := conn.SystemService().
+			TemplatesService().
+			TemplateService(a.templateID).
+			Remove().
+			Send()
+		if err != nil {
+			if _, ok := err.(*ovirtsdk4.NotFoundError); ok {
+				return nil
+			}
+			return fmt.Errorf("Error deleting template '%s': %s", a.templateID, err)
+		}
+		stateChange := StateChangeConf{
+			Pending: []string{string(ovirtsdk4.TEMPLATESTATUS_LOCKED), string(ovirtsdk4.TEMPLATESTATUS_OK)},
+			Target:  []string{""},
+			Refresh: TemplateStateRefreshFunc(conn, a.templateID),
+		}
+		// The refresh functions report an empty state once the object is gone
+		if _, err := WaitForState(&stateChange); err != nil {
+			return fmt.Errorf("Failed waiting for artifact (%s) to be deleted: %s", a.Id(), err)
+		}
+		return nil
+	}
+
+	diskIDs := a.diskIDs
+	if len(diskIDs) == 0 {
+		diskIDs = []string{a.diskID}
+	}
+	for _, diskID := range diskIDs {
+		log.Printf("Destroying disk: %s", diskID)
+		_, err := conn.SystemService().
+			DisksService().
+			DiskService(diskID).
+			Remove().
+			Send()
+		if err != nil {
+			if _, ok := err.(*ovirtsdk4.NotFoundError); ok {
+				continue
+			}
+			return fmt.Errorf("Error deleting disk '%s': %s", diskID, err)
+		}
+		stateChange := StateChangeConf{
+			Pending: []string{string(ovirtsdk4.DISKSTATUS_LOCKED), string(ovirtsdk4.DISKSTATUS_OK)},
+			Target:  []string{""},
+			Refresh: DiskStateRefreshFunc(conn, diskID),
+		}
+		if _, err := WaitForState(&stateChange); err != nil {
+			return fmt.Errorf("Failed waiting for disk (%s) to be deleted: %s", diskID, err)
+		}
+	}
+
 	return nil
 }
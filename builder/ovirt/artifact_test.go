@@ -1,9 +1,13 @@
 package ovirt
 
 import (
+    "errors"
+    "io/ioutil"
+    "os"
     "testing"
 
     "github.com/hashicorp/packer/packer"
+    ovirtsdk4 "github.com/ovirt/go-ovirt"
 )
 
 func TestArtifact_Impl(t *testing.T) {
@@ -34,3 +38,127 @@ func TestArtifactString(t *testing.T) {
         t.Fatalf("bad message returned: %s", result)
     }
 }
+
+func TestArtifactId_template(t *testing.T) {
+    expected := `0f8c8b3e-7c5d-4a8b-9a2e-4d1f5a3b2c1d`
+
+    a := &Artifact{
+        diskID:     "c2867299-28ea-48a2-922a-805b999fcb2d",
+        templateID: "0f8c8b3e-7c5d-4a8b-9a2e-4d1f5a3b2c1d",
+    }
+
+    result := a.Id()
+    if result != expected {
+        t.Fatalf("wrong artifact id returned: %s", result)
+    }
+}
+
+func TestArtifactString_template(t *testing.T) {
+    expected := "A template was created: 0f8c8b3e-7c5d-4a8b-9a2e-4d1f5a3b2c1d"
+
+    a := &Artifact{
+        templateID: "0f8c8b3e-7c5d-4a8b-9a2e-4d1f5a3b2c1d",
+    }
+    result := a.String()
+    if result != expected {
+        t.Fatalf("bad message returned: %s", result)
+    }
+}
+
+func TestArtifactString_additionalDisks(t *testing.T) {
+    expected := "Disks were created: c2867299-28ea-48a2-922a-805b999fcb2d, 5b6d1f0e-3a4c-4b8e-9f2d-7c1a0e9b8d3f"
+
+    a := &Artifact{
+        diskID:  "c2867299-28ea-48a2-922a-805b999fcb2d",
+        diskIDs: []string{"c2867299-28ea-48a2-922a-805b999fcb2d", "5b6d1f0e-3a4c-4b8e-9f2d-7c1a0e9b8d3f"},
+    }
+    result := a.String()
+    if result != expected {
+        t.Fatalf("bad message returned: %s", result)
+    }
+}
+
+func TestArtifactState(t *testing.T) {
+    a := &Artifact{
+        templateID: "0f8c8b3e-7c5d-4a8b-9a2e-4d1f5a3b2c1d",
+        stateData: map[string]interface{}{
+            "template_id":      "0f8c8b3e-7c5d-4a8b-9a2e-4d1f5a3b2c1d",
+            "template_version": int64(3),
+            "provisioned_size": int64(10737418240),
+        },
+    }
+    if result := a.State("template_version"); result != int64(3) {
+        t.Fatalf("wrong template version returned: %v", result)
+    }
+    if result := a.State("disk_id"); result != nil {
+        t.Fatalf("should not return unknown state: %v", result)
+    }
+
+    metadata, ok := a.State("atlas.artifact.metadata").(map[string]string)
+    if !ok {
+        t.Fatal("should return metadata map")
+    }
+    if metadata["template_id"] != "0f8c8b3e-7c5d-4a8b-9a2e-4d1f5a3b2c1d" {
+        t.Fatalf("wrong template id in metadata: %s", metadata["template_id"])
+    }
+    if metadata["provisioned_size"] != "10737418240" {
+        t.Fatalf("wrong provisioned size in metadata: %s", metadata["provisioned_size"])
+    }
+
+    a = &Artifact{
+        diskID: "c2867299-28ea-48a2-922a-805b999fcb2d",
+    }
+    if result := a.State("template_version"); result != nil {
+        t.Fatalf("should not return template version for disk: %v", result)
+    }
+}
+
+func TestArtifactFiles(t *testing.T) {
+    a := &Artifact{
+        diskID: "c2867299-28ea-48a2-922a-805b999fcb2d",
+    }
+    if result := a.Files(); result != nil {
+        t.Fatalf("should not return files: %v", result)
+    }
+
+    a.files = []string{"output/disk.qcow2"}
+    result := a.Files()
+    if len(result) != 1 || result[0] != "output/disk.qcow2" {
+        t.Fatalf("wrong files returned: %v", result)
+    }
+}
+
+func TestArtifactDestroy_files(t *testing.T) {
+    f, err := ioutil.TempFile("", "packer-ovirt-artifact")
+    if err != nil {
+        t.Fatalf("failed to create temp file: %s", err)
+    }
+    f.Close()
+    defer os.Remove(f.Name())
+
+    a := &Artifact{
+        files: []string{f.Name()},
+    }
+    if err := a.Destroy(); err != nil {
+        t.Fatalf("should not fail to destroy files: %s", err)
+    }
+    if _, err := os.Stat(f.Name()); !os.IsNotExist(err) {
+        t.Fatal("should delete artifact files")
+    }
+}
+
+func TestArtifactDestroy_noConnection(t *testing.T) {
+    a := &Artifact{
+        diskID: "c2867299-28ea-48a2-922a-805b999fcb2d",
+    }
+    if err := a.Destroy(); err == nil {
+        t.Fatal("should fail to destroy disk without connection")
+    }
+
+    a.connect = func() (*ovirtsdk4.Connection, error) {
+        return nil, errors.New("connection refused")
+    }
+    if err := a.Destroy(); err == nil {
+        t.Fatal("should fail to destroy disk when connecting fails")
+    }
+}
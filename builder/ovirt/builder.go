@@ -4,13 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"time"
 
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
-	ovirtsdk4 "github.com/ovirt/go-ovirt"
 )
 
 // BuilderID defines the unique id for the builder.
@@ -32,6 +30,12 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	}
 	b.config = *c
 
+	if b.config.PreflightCheck {
+		if err := preflightCheck(&b.config); err != nil {
+			return nil, err
+		}
+	}
+
 	return nil, nil
 }
 
@@ -39,19 +43,12 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 	var err error
 
-	conn, err := ovirtsdk4.NewConnectionBuilder().
-		URL(b.config.OvirtURL.String()).
-		Username(b.config.Username).
-		Password(b.config.Password).
-		Insecure(b.config.SkipCertValidation).
-		Compress(true).
-		Timeout(time.Second * 10).
-		Build()
+	conn, err := b.config.connect()
 	if err != nil {
 		return nil, fmt.Errorf("oVirt: Connection failed, reason: %s", err.Error())
 	}
 
-	defer conn.Close()
+	defer b.config.disconnect(conn)
 
 	log.Printf("Successfully connected to %s\n", b.config.OvirtURL.String())
 
@@ -64,28 +61,89 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 
 	// Build the steps
 	steps := []multistep.Step{}
-	steps = append(steps, &stepKeyPair{
-		Debug:        b.config.PackerDebug,
-		Comm:         &b.config.Comm,
-		DebugKeyPath: fmt.Sprintf("ovirt_%s.pem", b.config.PackerBuildName),
-	},
-	)
-	if b.config.SourceType == "template" {
+	if b.config.Comm.Type == "ssh" {
+		steps = append(steps, &stepKeyPair{
+			Debug:        b.config.PackerDebug,
+			Comm:         &b.config.Comm,
+			DebugKeyPath: fmt.Sprintf("ovirt_%s.pem", b.config.PackerBuildName),
+		},
+		)
+	}
+	switch b.config.SourceType {
+	case "disk":
+		steps = append(steps, &stepCreateVMFromDisk{
+			Ctx:   b.config.ctx,
+			Debug: b.config.PackerDebug,
+		},
+		)
+	case "image":
+		imageURL := b.config.SourceImageURL
+		if imageURL == "" {
+			imageURL = b.config.SourceImageFile
+		}
+		steps = append(steps, &common.StepDownload{
+			Checksum:     b.config.SourceImageChecksum,
+			ChecksumType: b.config.SourceImageChecksumType,
+			Description:  "Image",
+			ResultKey:    "image_path",
+			Url:          []string{imageURL},
+		},
+		)
+		steps = append(steps, &stepCreateVMFromImage{
+			Ctx:   b.config.ctx,
+			Debug: b.config.PackerDebug,
+		},
+		)
+	case "iso":
+		steps = append(steps, &stepCreateVMFromISO{
+			Ctx:   b.config.ctx,
+			Debug: b.config.PackerDebug,
+		},
+		)
+	case "template":
 		steps = append(steps, &stepCreateVMFromTemplate{
 			Ctx:   b.config.ctx,
 			Debug: b.config.PackerDebug,
 		},
 		)
+	case "vm":
+		steps = append(steps, &stepCloneVM{
+			Ctx:   b.config.ctx,
+			Debug: b.config.PackerDebug,
+		},
+		)
+	}
+	steps = append(steps, &stepFindBootDisk{})
+	if (b.config.DiskSize > 0) && (b.config.SourceType != "iso") {
+		// Disks created for ISOs already get the requested size. Disks
+		// uploaded from images must match the virtual size of the image
+		// and are grown afterwards.
+		steps = append(steps, &stepResizeDisk{})
+	}
+	if len(b.config.AdditionalDisks) > 0 {
+		steps = append(steps, &stepCreateAdditionalDisks{})
+	}
+	if len(b.config.vnicInterfaces()) > 0 {
+		steps = append(steps, &stepSetupNetworkInterfaces{})
 	}
 	steps = append(steps, &stepSetupInitialRun{
 		Debug: b.config.PackerDebug,
 		Comm:  &b.config.Comm,
+		Ctx:   b.config.ctx,
 	},
 	)
+	if (b.config.staticIPAddress() == "") && (b.config.Comm.Host() == "") {
+		steps = append(steps, &stepWaitForIP{
+			Timeout:    b.config.IPWaitTimeout,
+			Preference: b.config.IPPreference,
+		},
+		)
+	}
 	steps = append(steps, &communicator.StepConnect{
-		Config:    &b.config.Comm,
-		Host:      commHost,
-		SSHConfig: b.config.Comm.SSHConfigFunc(),
+		Config:      &b.config.Comm,
+		Host:        commHost,
+		SSHConfig:   b.config.Comm.SSHConfigFunc(),
+		WinRMConfig: winRMConfig,
 	},
 	)
 	steps = append(steps, &common.StepProvision{})
@@ -94,8 +152,18 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 	},
 	)
 	steps = append(steps, &stepStopVM{})
+	if b.config.SourceType == "iso" {
+		steps = append(steps, &stepEjectCdrom{})
+	}
 	steps = append(steps, &stepUpdateDisk{})
-	steps = append(steps, &stepDetachDisk{})
+	if b.config.TemplateName != "" {
+		steps = append(steps, &stepCreateTemplate{})
+	} else {
+		steps = append(steps, &stepDetachDisk{})
+		if b.config.ExportLocalPath != "" {
+			steps = append(steps, &stepDownloadDisk{})
+		}
+	}
 
 	// To use `Must` methods, you should recover it if panics
 	defer func() {
@@ -113,6 +181,39 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 		return nil, rawErr.(error)
 	}
 
+	// Collect the details about the built image
+	stateData := make(map[string]interface{})
+	for _, key := range []string{
+		"actual_size",
+		"cluster_id",
+		"disk_format",
+		"disk_id",
+		"disk_ids",
+		"disk_name",
+		"disk_profile",
+		"disk_sparse",
+		"provisioned_size",
+		"source_template_id",
+		"storage_domain",
+		"template_id",
+		"template_version",
+	} {
+		if value, ok := state.GetOk(key); ok {
+			stateData[key] = value
+		}
+	}
+
+	// A template takes precedence over a floating disk
+	if templateID, ok := state.GetOk("template_id"); ok {
+		artifact := &Artifact{
+			templateID: templateID.(string),
+			stateData:  stateData,
+			connect:    b.config.connect,
+			disconnect: b.config.disconnect,
+		}
+		return artifact, nil
+	}
+
 	// If there are no images, then just return
 	if _, ok := state.GetOk("disk_id"); !ok {
 		return nil, nil
@@ -120,7 +221,16 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 
 	// Build the artifact and return it
 	artifact := &Artifact{
-		diskID: state.Get("disk_id").(string),
+		diskID:     state.Get("disk_id").(string),
+		stateData:  stateData,
+		connect:    b.config.connect,
+		disconnect: b.config.disconnect,
+	}
+	if diskIDs, ok := state.GetOk("disk_ids"); ok {
+		artifact.diskIDs = diskIDs.([]string)
+	}
+	if exportPath, ok := state.GetOk("export_path"); ok {
+		artifact.files = []string{exportPath.(string)}
 	}
 
 	return artifact, nil
@@ -1,8 +1,16 @@
 package ovirt
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/common/uuid"
@@ -10,8 +18,11 @@ import (
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
 )
 
+var domainLabelRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
@@ -20,13 +31,66 @@ type Config struct {
 
 	Comm communicator.Config `mapstructure:",squash"`
 
-	VMName      string       `mapstructure:"vm_name"`
-	IPAddress   string       `mapstructure:"address"`
-	Netmask     string       `mapstructure:"netmask"`
-	Gateway     string       `mapstructure:"gateway"`
+	PreflightCheck bool `mapstructure:"preflight_check"`
+
+	VMName    string `mapstructure:"vm_name"`
+	IPAddress string `mapstructure:"address"`
+	Netmask   string `mapstructure:"netmask"`
+	Gateway   string `mapstructure:"gateway"`
+
+	IPv6Address      string `mapstructure:"ipv6_address"`
+	IPv6Prefix       int    `mapstructure:"ipv6_prefix"`
+	IPv6Gateway      string `mapstructure:"ipv6_gateway"`
+	IPv6BootProtocol string `mapstructure:"ipv6_boot_protocol"`
+
+	Network           string             `mapstructure:"network"`
+	VnicProfile       string             `mapstructure:"vnic_profile"`
+	NetworkInterfaces []NetworkInterface `mapstructure:"network_interfaces"`
+
+	DNSServers []string `mapstructure:"dns_servers"`
+	DNSSearch  []string `mapstructure:"dns_search"`
+	Hostname   string   `mapstructure:"hostname"`
+	Timezone   string   `mapstructure:"timezone"`
+
+	InitializationType   string `mapstructure:"initialization_type"`
+	SysprepFile          string `mapstructure:"sysprep_file"`
+	SysprepAdminPassword string `mapstructure:"sysprep_admin_password"`
+	SysprepDomain        string `mapstructure:"sysprep_domain"`
+	SysprepDomainOU      string `mapstructure:"sysprep_domain_ou"`
+	UserData             string `mapstructure:"user_data"`
+	UserDataFile         string `mapstructure:"user_data_file"`
+
+	Memory       int    `mapstructure:"memory"`
+	MaxMemory    int    `mapstructure:"max_memory"`
+	CPUSockets   int    `mapstructure:"cpu_sockets"`
+	CPUCores     int    `mapstructure:"cpu_cores"`
+	CPUThreads   int    `mapstructure:"cpu_threads"`
+	BiosType     string `mapstructure:"bios_type"`
+	OSType       string `mapstructure:"os_type"`
+	InstanceType string `mapstructure:"instance_type"`
+
+	IPWaitTimeout time.Duration `mapstructure:"ip_wait_timeout"`
+	IPPreference  string        `mapstructure:"ip_preference"`
 
-	DiskName        string `mapstructure:"disk_name"`
-	DiskDescription string `mapstructure:"disk_description"`
+	DiskName        string         `mapstructure:"disk_name"`
+	DiskDescription string         `mapstructure:"disk_description"`
+	DiskSize        int            `mapstructure:"disk_size"`
+	StorageDomain   string         `mapstructure:"storage_domain"`
+	DiskFormat      string         `mapstructure:"disk_format"`
+	DiskSparse      config.Trilean `mapstructure:"disk_sparse"`
+	DiskProfile     string         `mapstructure:"disk_profile"`
+	DiskSelector    string         `mapstructure:"disk_selector"`
+
+	AdditionalDisks []AdditionalDisk `mapstructure:"additional_disks"`
+
+	ExportLocalPath string `mapstructure:"export_local_path"`
+	ExportFormat    string `mapstructure:"export_format"`
+
+	TemplateName        string `mapstructure:"template_name"`
+	TemplateDescription string `mapstructure:"template_description"`
+	TemplateCluster     string `mapstructure:"template_cluster"`
+	TemplateBaseName    string `mapstructure:"template_base_name"`
+	TemplateVersionName string `mapstructure:"template_version_name"`
 
 	ctx interpolate.Context
 }
@@ -54,6 +118,259 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	if c.DiskName == "" {
 		c.DiskName = c.VMName
 	}
+	if c.DiskSize < 0 {
+		errs = packer.MultiErrorAppend(errs, errors.New("disk_size must not be negative"))
+	}
+	if (c.SourceType == "iso") && (c.DiskSize < 1) {
+		c.DiskSize = 10
+		log.Printf("Using default disk_size: %d GiB", c.DiskSize)
+	}
+	if ((c.SourceType == "image") || (c.SourceType == "iso")) && (c.StorageDomain == "") {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("storage_domain must be specified for source_type %s", c.SourceType))
+	}
+	if c.DiskFormat != "" {
+		c.DiskFormat = strings.ToLower(c.DiskFormat)
+		if (c.DiskFormat != "cow") && (c.DiskFormat != "raw") {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid disk_format: %s", c.DiskFormat))
+		}
+	}
+	if ((c.DiskFormat != "") || (c.DiskSparse != config.TriUnset) || (c.DiskProfile != "")) && (c.SourceType != "iso") && (c.SourceType != "template") {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("disk_format, disk_sparse and disk_profile can't be used with source_type %s", c.SourceType))
+	}
+	if (c.DiskSelector != "") && (c.SourceType != "template") && (c.SourceType != "vm") {
+		// Only copies of templates and VMs may have multiple disks
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("disk_selector can't be used with source_type %s", c.SourceType))
+	}
+	for i := range c.AdditionalDisks {
+		defaultName := fmt.Sprintf("%s-%d", c.DiskName, i+1)
+		errs = packer.MultiErrorAppend(errs, c.AdditionalDisks[i].Prepare(defaultName, c.StorageDomain)...)
+	}
+	if c.TemplateBaseName != "" {
+		if c.TemplateName == "" {
+			// A template version always carries the name of its base template
+			c.TemplateName = c.TemplateBaseName
+			log.Printf("Using default template_name: %s", c.TemplateName)
+		} else if c.TemplateName != c.TemplateBaseName {
+			errs = packer.MultiErrorAppend(errs, errors.New("Conflict: template_name must match template_base_name when creating a template version"))
+		}
+	}
+	if (c.TemplateVersionName != "") && (c.TemplateBaseName == "") {
+		errs = packer.MultiErrorAppend(errs, errors.New("template_base_name must be specified when setting template_version_name"))
+	}
+	if (c.TemplateName != "") && (c.TemplateCluster == "") {
+		c.TemplateCluster = c.Cluster
+		log.Printf("Using default template_cluster: %s", c.TemplateCluster)
+	}
+	if c.ExportLocalPath != "" {
+		// The disk is downloaded as is, so the file format is given by
+		// the disk format if it is known in advance
+		diskFormat := c.DiskFormat
+		if (diskFormat == "") && (c.SourceType == "iso") {
+			diskFormat = "cow"
+		}
+		if c.ExportFormat == "" {
+			c.ExportFormat = "qcow2"
+			if diskFormat == "raw" {
+				c.ExportFormat = "raw"
+			}
+			log.Printf("Using default export_format: %s", c.ExportFormat)
+		}
+		if (c.ExportFormat != "qcow2") && (c.ExportFormat != "raw") {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid export_format: %s", c.ExportFormat))
+		} else if (diskFormat != "") && (exportFormat(diskFormat) != c.ExportFormat) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Conflict: export_format %s doesn't match disk_format %s, the disk isn't converted", c.ExportFormat, diskFormat))
+		}
+		if fi, err := os.Stat(c.ExportLocalPath); err == nil && fi.IsDir() {
+			c.ExportLocalPath = filepath.Join(c.ExportLocalPath, fmt.Sprintf("%s.%s", c.DiskName, c.ExportFormat))
+			log.Printf("Using export_local_path: %s", c.ExportLocalPath)
+		}
+		if fi, err := os.Stat(filepath.Dir(c.ExportLocalPath)); err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Directory of export_local_path is not accessible: %s", err))
+		} else if !fi.IsDir() {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Directory of export_local_path is not a directory: %s", filepath.Dir(c.ExportLocalPath)))
+		}
+		if c.TemplateName != "" {
+			errs = packer.MultiErrorAppend(errs, errors.New("Conflict: export_local_path can't be used together with template_name"))
+		}
+	}
+	if (c.Memory < 0) || (c.MaxMemory < 0) {
+		errs = packer.MultiErrorAppend(errs, errors.New("memory and max_memory must not be negative"))
+	}
+	if (c.Memory > 0) && (c.MaxMemory == 0) {
+		// oVirt rejects a maximum memory below the defined memory
+		c.MaxMemory = 4 * c.Memory
+		log.Printf("Using default max_memory: %d MiB", c.MaxMemory)
+	}
+	if (c.MaxMemory > 0) && (c.Memory == 0) {
+		errs = packer.MultiErrorAppend(errs, errors.New("memory must be specified when setting max_memory"))
+	} else if (c.MaxMemory > 0) && (c.MaxMemory < c.Memory) {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("max_memory must be at least memory (%d MiB)", c.Memory))
+	}
+	if (c.CPUSockets < 0) || (c.CPUCores < 0) || (c.CPUThreads < 0) {
+		errs = packer.MultiErrorAppend(errs, errors.New("cpu_sockets, cpu_cores and cpu_threads must not be negative"))
+	} else if (c.CPUSockets > 0) || (c.CPUCores > 0) || (c.CPUThreads > 0) {
+		// The CPU topology is always replaced as a whole
+		if c.CPUSockets == 0 {
+			c.CPUSockets = 1
+		}
+		if c.CPUCores == 0 {
+			c.CPUCores = 1
+		}
+		if c.CPUThreads == 0 {
+			c.CPUThreads = 1
+		}
+	}
+	if c.BiosType != "" {
+		validBiosTypes := []string{"i440fx_sea_bios", "q35_ovmf", "q35_sea_bios", "q35_secure_boot"}
+		c.BiosType = strings.ToLower(c.BiosType)
+		i := sort.SearchStrings(validBiosTypes, c.BiosType)
+		if (i >= len(validBiosTypes)) || (validBiosTypes[i] != c.BiosType) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid bios_type: %s", c.BiosType))
+		}
+	}
+	if c.InitializationType == "" {
+		c.InitializationType = "cloud-init"
+		log.Printf("Using default initialization_type: %s", c.InitializationType)
+	}
+	if (c.InitializationType != "cloud-init") && (c.InitializationType != "sysprep") {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid initialization_type: %s", c.InitializationType))
+	}
+	if c.InitializationType == "sysprep" {
+		if c.SysprepFile != "" {
+			if _, err := os.Stat(c.SysprepFile); err != nil {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf("sysprep_file is not accessible: %s", err))
+			}
+		}
+		if (c.SysprepAdminPassword == "") && (c.Comm.Type == "winrm") {
+			// Let the WinRM communicator log in with the configured password
+			c.SysprepAdminPassword = c.Comm.WinRMPassword
+		}
+	} else if (c.SysprepFile != "") || (c.SysprepAdminPassword != "") || (c.SysprepDomain != "") || (c.SysprepDomainOU != "") {
+		errs = packer.MultiErrorAppend(errs, errors.New("sysprep options require initialization_type sysprep"))
+	}
+	if c.InitializationType == "cloud-init" {
+		if (c.UserData != "") && (c.UserDataFile != "") {
+			errs = packer.MultiErrorAppend(errs, errors.New("Conflict: Set either user_data or user_data_file"))
+		}
+		if c.UserDataFile != "" {
+			if _, err := os.Stat(c.UserDataFile); err != nil {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf("user_data_file is not accessible: %s", err))
+			}
+		}
+	} else if (c.UserData != "") || (c.UserDataFile != "") {
+		errs = packer.MultiErrorAppend(errs, errors.New("user_data options require initialization_type cloud-init"))
+	}
+	for i := range c.NetworkInterfaces {
+		defaultName := fmt.Sprintf("eth%d", i)
+		errs = packer.MultiErrorAppend(errs, c.NetworkInterfaces[i].Prepare(defaultName)...)
+	}
+	if ((c.Network != "") || (c.VnicProfile != "")) && (len(c.NetworkInterfaces) > 0) {
+		errs = packer.MultiErrorAppend(errs, errors.New("Conflict: Set either network and vnic_profile or network_interfaces"))
+	}
+	if (c.SourceType == "disk") || (c.SourceType == "image") || (c.SourceType == "iso") {
+		// VMs created from the Blank template have no vNIC to connect with
+		if (len(c.NetworkInterfaces) == 0) && (c.Network == "") && (c.VnicProfile == "") {
+			c.Network = "ovirtmgmt"
+			log.Printf("Using default network: %s", c.Network)
+		}
+	} else if (c.Network != "") || (c.VnicProfile != "") {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("network and vnic_profile can't be used with source_type %s, use network_interfaces", c.SourceType))
+	}
+	if (c.IPAddress != "") && (len(c.NetworkInterfaces) > 0) {
+		errs = packer.MultiErrorAppend(errs, errors.New("Conflict: Set either address or network_interfaces"))
+	}
+	if c.IPAddress != "" {
+		if ip := net.ParseIP(c.IPAddress); (ip == nil) || (ip.To4() == nil) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid address: %s", c.IPAddress))
+		}
+	}
+	if c.Netmask != "" {
+		if ip := net.ParseIP(c.Netmask); (ip == nil) || (ip.To4() == nil) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid netmask: %s", c.Netmask))
+		}
+	}
+	if c.Gateway != "" {
+		if ip := net.ParseIP(c.Gateway); (ip == nil) || (ip.To4() == nil) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid gateway: %s", c.Gateway))
+		}
+	}
+	if (c.IPv6BootProtocol == "") && (c.IPv6Address != "") {
+		c.IPv6BootProtocol = "static"
+		log.Printf("Using default ipv6_boot_protocol: %s", c.IPv6BootProtocol)
+	}
+	c.IPv6BootProtocol = strings.ToLower(c.IPv6BootProtocol)
+	switch c.IPv6BootProtocol {
+	case "":
+		if (c.IPv6Prefix != 0) || (c.IPv6Gateway != "") {
+			errs = packer.MultiErrorAppend(errs, errors.New("ipv6_prefix and ipv6_gateway require ipv6_address"))
+		}
+	case "static":
+		if ip := net.ParseIP(c.IPv6Address); (ip == nil) || (ip.To4() != nil) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid ipv6_address: %s", c.IPv6Address))
+		}
+		if c.IPv6Prefix == 0 {
+			c.IPv6Prefix = 64
+			log.Printf("Using default ipv6_prefix: %d", c.IPv6Prefix)
+		}
+		if (c.IPv6Prefix < 1) || (c.IPv6Prefix > 128) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid ipv6_prefix: %d", c.IPv6Prefix))
+		}
+		if c.IPv6Gateway != "" {
+			if ip := net.ParseIP(c.IPv6Gateway); (ip == nil) || (ip.To4() != nil) {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid ipv6_gateway: %s", c.IPv6Gateway))
+			}
+		}
+	case "autoconf", "dhcp":
+		if (c.IPv6Address != "") || (c.IPv6Prefix != 0) || (c.IPv6Gateway != "") {
+			errs = packer.MultiErrorAppend(errs, errors.New("ipv6_address, ipv6_prefix and ipv6_gateway require ipv6_boot_protocol static"))
+		}
+		if c.IPPreference == "" {
+			// Connect to the dynamically assigned IPv6 address
+			c.IPPreference = "ipv6"
+			log.Printf("Using default ip_preference: %s", c.IPPreference)
+		}
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid ipv6_boot_protocol: %s", c.IPv6BootProtocol))
+	}
+	if (c.IPv6BootProtocol != "") && (len(c.NetworkInterfaces) > 0) {
+		errs = packer.MultiErrorAppend(errs, errors.New("Conflict: Set either IPv6 options or network_interfaces"))
+	}
+	for _, server := range c.DNSServers {
+		if net.ParseIP(server) == nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid dns_servers entry: %s", server))
+		}
+	}
+	for _, domain := range c.DNSSearch {
+		if !validDomainName(domain) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid dns_search entry: %s", domain))
+		}
+	}
+	if c.Hostname != "" {
+		if !validDomainName(c.Hostname) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid hostname: %s", c.Hostname))
+		} else if (c.InitializationType == "sysprep") && (len(c.Hostname) > 15) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("hostname must not be longer than 15 characters for sysprep: %s", c.Hostname))
+		}
+	}
+	if (c.Timezone != "") && (c.InitializationType == "cloud-init") {
+		// Windows uses its own time zone names which can't be checked here
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid timezone: %s", c.Timezone))
+		}
+	}
+	if c.staticIPAddress() == "" {
+		if c.IPWaitTimeout == 0 {
+			c.IPWaitTimeout = 10 * time.Minute
+			log.Printf("Using default ip_wait_timeout: %s", c.IPWaitTimeout)
+		}
+		if c.IPPreference == "" {
+			c.IPPreference = "ipv4"
+			log.Printf("Using default ip_preference: %s", c.IPPreference)
+		}
+		if (c.IPPreference != "ipv4") && (c.IPPreference != "ipv6") {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("Invalid ip_preference: %s", c.IPPreference))
+		}
+	}
 	if c.Netmask == "" {
 		c.Netmask = "255.255.255.0"
 		log.Printf("Set default netmask to %s", c.Netmask)
@@ -65,6 +382,66 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		return nil, nil, errs
 	}
 
-	packer.LogSecretFilter.Set(c.Password)
+	packer.LogSecretFilter.Set(c.Password, c.SysprepAdminPassword)
 	return c, nil, nil
 }
+
+// validDomainName reports whether name is a valid DNS host or domain name.
+func validDomainName(name string) bool {
+	if len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if !domainLabelRegexp.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// exportFormat returns the file format of a downloaded disk with the given
+// oVirt disk format.
+func exportFormat(diskFormat string) string {
+	if diskFormat == string(ovirtsdk4.DISKFORMAT_RAW) {
+		return "raw"
+	}
+	return "qcow2"
+}
+
+// vnicInterfaces returns the vNICs to add to the VM. Unless
+// network_interfaces is set, a single vNIC is added for network or
+// vnic_profile.
+func (c *Config) vnicInterfaces() []NetworkInterface {
+	if len(c.NetworkInterfaces) > 0 {
+		return c.NetworkInterfaces
+	}
+	if (c.Network == "") && (c.VnicProfile == "") {
+		return nil
+	}
+	return []NetworkInterface{
+		{
+			Name:        "eth0",
+			Network:     c.Network,
+			VnicProfile: c.VnicProfile,
+			Interface:   "virtio",
+		},
+	}
+}
+
+// staticIPAddress returns the first static IP address configured for the VM
+// or an empty string if the address is assigned dynamically. IPv6 addresses
+// take precedence.
+func (c *Config) staticIPAddress() string {
+	if c.IPv6Address != "" {
+		return c.IPv6Address
+	}
+	if c.IPAddress != "" {
+		return c.IPAddress
+	}
+	for _, n := range c.NetworkInterfaces {
+		if n.BootProtocol == "static" {
+			return n.IPAddress
+		}
+	}
+	return ""
+}
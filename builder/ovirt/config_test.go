@@ -0,0 +1,441 @@
+package ovirt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewConfig(t *testing.T) {
+	c, _, errs := NewConfig(testConfig())
+	if errs != nil {
+		t.Fatalf("should not fail to initialize minimal config: %s", errs)
+	}
+	if c.InitializationType != "cloud-init" {
+		t.Fatalf("should default to cloud-init initialization: %s", c.InitializationType)
+	}
+}
+
+func TestNewConfig_initializationType(t *testing.T) {
+	raw := testConfig()
+	raw["communicator"] = "winrm"
+	raw["winrm_username"] = "Administrator"
+	raw["winrm_password"] = "Secret123!"
+	raw["initialization_type"] = "sysprep"
+	raw["sysprep_domain"] = "example.com"
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize sysprep config: %s", errs)
+	}
+	if c.SysprepAdminPassword != "Secret123!" {
+		t.Fatalf("should default admin password to winrm_password: %s", c.SysprepAdminPassword)
+	}
+
+	raw = testConfig()
+	raw["initialization_type"] = "ignition"
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept invalid initialization type")
+	}
+
+	raw = testConfig()
+	raw["sysprep_domain"] = "example.com"
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept sysprep options with cloud-init")
+	}
+
+	raw = testConfig()
+	raw["initialization_type"] = "sysprep"
+	raw["sysprep_file"] = "/nonexistent/unattend.xml"
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept missing sysprep file")
+	}
+}
+
+func TestNewConfig_userData(t *testing.T) {
+	raw := testConfig()
+	raw["user_data"] = "#cloud-config\npackages:\n  - git\n"
+	if _, _, errs := NewConfig(raw); errs != nil {
+		t.Fatalf("should not fail to initialize user data config: %s", errs)
+	}
+
+	f, err := ioutil.TempFile("", "packer-ovirt-user-data")
+	if err != nil {
+		t.Fatalf("should create temporary file: %s", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	raw["user_data_file"] = f.Name()
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept user_data together with user_data_file")
+	}
+
+	delete(raw, "user_data")
+	if _, _, errs := NewConfig(raw); errs != nil {
+		t.Fatalf("should not fail to initialize user data file config: %s", errs)
+	}
+
+	raw = testConfig()
+	raw["user_data_file"] = "/nonexistent/user-data"
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept missing user data file")
+	}
+
+	raw = testConfig()
+	raw["communicator"] = "winrm"
+	raw["winrm_username"] = "Administrator"
+	raw["initialization_type"] = "sysprep"
+	raw["user_data"] = "#cloud-config\n"
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept user data with sysprep")
+	}
+}
+
+func TestNewConfig_export(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-ovirt")
+	if err != nil {
+		t.Fatalf("should create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	raw := testConfig()
+	raw["disk_name"] = "centos"
+	raw["disk_format"] = "raw"
+	raw["export_local_path"] = dir
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize export config: %s", errs)
+	}
+	if c.ExportFormat != "raw" {
+		t.Fatalf("should default export_format to the disk format: %s", c.ExportFormat)
+	}
+	if c.ExportLocalPath != filepath.Join(dir, "centos.raw") {
+		t.Fatalf("should export into directory: %s", c.ExportLocalPath)
+	}
+
+	raw["export_format"] = "qcow2"
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept export_format not matching disk_format")
+	}
+
+	raw = testConfig()
+	raw["export_local_path"] = filepath.Join(dir, "nonexistent", "disk.qcow2")
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept missing export directory")
+	}
+}
+
+func TestNewConfig_hardware(t *testing.T) {
+	raw := testConfig()
+	raw["memory"] = 4096
+	raw["cpu_cores"] = 4
+	raw["bios_type"] = "Q35_SECURE_BOOT"
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize hardware config: %s", errs)
+	}
+	if c.MaxMemory != 16384 {
+		t.Fatalf("should default max_memory to four times memory: %d", c.MaxMemory)
+	}
+	if (c.CPUSockets != 1) || (c.CPUCores != 4) || (c.CPUThreads != 1) {
+		t.Fatalf("should complete CPU topology: %d/%d/%d", c.CPUSockets, c.CPUCores, c.CPUThreads)
+	}
+	if c.BiosType != "q35_secure_boot" {
+		t.Fatalf("should lowercase bios_type: %s", c.BiosType)
+	}
+
+	raw = testConfig()
+	raw["memory"] = 4096
+	raw["max_memory"] = 2048
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept max_memory below memory")
+	}
+
+	raw = testConfig()
+	raw["max_memory"] = 2048
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept max_memory without memory")
+	}
+
+	raw = testConfig()
+	raw["cpu_threads"] = -1
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept negative CPU topology")
+	}
+
+	raw = testConfig()
+	raw["bios_type"] = "uefi"
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept invalid bios_type")
+	}
+}
+
+func TestNewConfig_diskFormat(t *testing.T) {
+	raw := testConfig()
+	raw["storage_domain"] = "data"
+	raw["disk_format"] = "RAW"
+	raw["disk_sparse"] = false
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize disk format config: %s", errs)
+	}
+	if c.DiskFormat != "raw" {
+		t.Fatalf("should lowercase disk_format: %s", c.DiskFormat)
+	}
+	if !c.DiskSparse.False() {
+		t.Fatalf("should set disk_sparse: %s", c.DiskSparse.ToString())
+	}
+
+	raw = testConfig()
+	raw["disk_format"] = "qcow2"
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept invalid disk_format")
+	}
+
+	raw = testConfig()
+	raw["source_type"] = "vm"
+	raw["source_vm_name"] = "centos"
+	raw["disk_profile"] = "gold"
+	delete(raw, "source_template_name")
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept disk options with source_type vm")
+	}
+}
+
+func TestNewConfig_diskSelector(t *testing.T) {
+	raw := testConfig()
+	raw["disk_selector"] = "centos"
+	if _, _, errs := NewConfig(raw); errs != nil {
+		t.Fatalf("should not fail to initialize disk selector config: %s", errs)
+	}
+
+	delete(raw, "source_template_name")
+	raw["source_type"] = "iso"
+	raw["source_iso_file"] = "CentOS-7-x86_64-Minimal-1810.iso"
+	raw["storage_domain"] = "data"
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept disk_selector for source type iso")
+	}
+}
+
+func TestNewConfig_additionalDisks(t *testing.T) {
+	raw := testConfig()
+	raw["storage_domain"] = "data"
+	raw["additional_disks"] = []map[string]interface{}{
+		{"size": 20},
+		{"name": "swap", "size": 2, "interface": "VIRTIO_SCSI", "format": "raw", "storage_domain": "fast"},
+	}
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize additional disks config: %s", errs)
+	}
+	disk := c.AdditionalDisks[0]
+	if (disk.Name != c.DiskName+"-1") || (disk.Interface != "virtio") || (disk.Format != "cow") || (disk.StorageDomain != "data") {
+		t.Fatalf("should set defaults of additional disk: %+v", disk)
+	}
+	disk = c.AdditionalDisks[1]
+	if (disk.Name != "swap") || (disk.Interface != "virtio_scsi") || (disk.Format != "raw") || (disk.StorageDomain != "fast") {
+		t.Fatalf("should keep settings of additional disk: %+v", disk)
+	}
+
+	raw = testConfig()
+	raw["additional_disks"] = []map[string]interface{}{
+		{"size": 20},
+	}
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept additional disk without storage domain")
+	}
+
+	raw = testConfig()
+	raw["storage_domain"] = "data"
+	raw["additional_disks"] = []map[string]interface{}{
+		{"interface": "ide"},
+	}
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept additional disk without size and invalid interface")
+	}
+}
+
+func TestNewConfig_networkInterfaces(t *testing.T) {
+	raw := testConfig()
+	delete(raw, "address")
+	raw["network_interfaces"] = []map[string]interface{}{
+		{"network": "build", "address": "10.0.0.10", "gateway": "10.0.0.1"},
+		{"vnic_profile": "storage", "interface": "E1000", "mac_address": "56:6f:1a:2b:00:01"},
+	}
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize network interfaces config: %s", errs)
+	}
+	n := c.NetworkInterfaces[0]
+	if (n.Name != "eth0") || (n.BootProtocol != "static") || (n.Netmask != "255.255.255.0") || (n.Interface != "virtio") {
+		t.Fatalf("should set defaults of static network interface: %+v", n)
+	}
+	n = c.NetworkInterfaces[1]
+	if (n.Name != "eth1") || (n.BootProtocol != "dhcp") || (n.Interface != "e1000") {
+		t.Fatalf("should set defaults of dhcp network interface: %+v", n)
+	}
+	if c.staticIPAddress() != "10.0.0.10" {
+		t.Fatalf("should use address of static network interface: %s", c.staticIPAddress())
+	}
+
+	raw = testConfig()
+	raw["network_interfaces"] = []map[string]interface{}{
+		{"network": "build"},
+	}
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept address together with network_interfaces")
+	}
+
+	raw = testConfig()
+	delete(raw, "address")
+	raw["network_interfaces"] = []map[string]interface{}{
+		{"mac_address": "invalid", "boot_protocol": "static"},
+	}
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept invalid network interface")
+	}
+}
+
+func TestNewConfig_network(t *testing.T) {
+	raw := testConfig()
+	delete(raw, "source_template_name")
+	raw["source_type"] = "iso"
+	raw["source_iso_file"] = "CentOS-7-x86_64-Minimal-1810.iso"
+	raw["storage_domain"] = "data"
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize iso config: %s", errs)
+	}
+	nics := c.vnicInterfaces()
+	if (len(nics) != 1) || (nics[0].Network != "ovirtmgmt") || (nics[0].Name != "eth0") {
+		t.Fatalf("should add a default vNIC for source type iso: %+v", nics)
+	}
+
+	raw["vnic_profile"] = "build"
+	if c, _, errs = NewConfig(raw); errs != nil {
+		t.Fatalf("should not fail to initialize vnic_profile config: %s", errs)
+	}
+	if nics := c.vnicInterfaces(); (len(nics) != 1) || (nics[0].Network != "") || (nics[0].VnicProfile != "build") {
+		t.Fatalf("should add a vNIC for vnic_profile: %+v", nics)
+	}
+
+	delete(raw, "address")
+	raw["network_interfaces"] = []map[string]interface{}{
+		{"network": "build"},
+	}
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept vnic_profile together with network_interfaces")
+	}
+
+	raw = testConfig()
+	raw["network"] = "build"
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept network for source type template")
+	}
+
+	c, _, errs = NewConfig(testConfig())
+	if errs != nil {
+		t.Fatalf("should not fail to initialize minimal config: %s", errs)
+	}
+	if nics := c.vnicInterfaces(); nics != nil {
+		t.Fatalf("should keep the vNICs of the source template: %+v", nics)
+	}
+}
+
+func TestNewConfig_ipv6(t *testing.T) {
+	raw := testConfig()
+	raw["ipv6_address"] = "2001:db8::10"
+	raw["ipv6_gateway"] = "2001:db8::1"
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize IPv6 config: %s", errs)
+	}
+	if (c.IPv6BootProtocol != "static") || (c.IPv6Prefix != 64) {
+		t.Fatalf("should set IPv6 defaults: %s/%d", c.IPv6BootProtocol, c.IPv6Prefix)
+	}
+	if c.staticIPAddress() != "2001:db8::10" {
+		t.Fatalf("should prefer IPv6 address: %s", c.staticIPAddress())
+	}
+
+	raw = testConfig()
+	delete(raw, "address")
+	raw["ipv6_boot_protocol"] = "autoconf"
+	c, _, errs = NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize IPv6 autoconf config: %s", errs)
+	}
+	if c.IPPreference != "ipv6" {
+		t.Fatalf("should prefer discovered IPv6 address: %s", c.IPPreference)
+	}
+
+	for key, value := range map[string]interface{}{
+		"address":            "2001:db8::10",
+		"gateway":            "192.168.0.256",
+		"ipv6_address":       "192.168.0.10",
+		"ipv6_prefix":        129,
+		"ipv6_boot_protocol": "slaac",
+	} {
+		raw = testConfig()
+		raw[key] = value
+		if _, _, errs := NewConfig(raw); errs == nil {
+			t.Fatalf("should not accept invalid %s: %v", key, value)
+		}
+	}
+
+	raw = testConfig()
+	raw["ipv6_gateway"] = "2001:db8::1"
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept ipv6_gateway without ipv6_address")
+	}
+}
+
+func TestNewConfig_dns(t *testing.T) {
+	raw := testConfig()
+	raw["dns_servers"] = []string{"192.168.0.1", "2001:db8::53"}
+	raw["dns_search"] = []string{"example.com", "build.example.com."}
+	raw["hostname"] = "build01.example.com"
+	raw["timezone"] = "UTC"
+	if _, _, errs := NewConfig(raw); errs != nil {
+		t.Fatalf("should not fail to initialize DNS config: %s", errs)
+	}
+
+	for key, value := range map[string]interface{}{
+		"dns_servers": []string{"ns1.example.com"},
+		"dns_search":  []string{"-example.com"},
+		"hostname":    "build_01",
+		"timezone":    "Mars/Olympus_Mons",
+	} {
+		raw = testConfig()
+		raw[key] = value
+		if _, _, errs := NewConfig(raw); errs == nil {
+			t.Fatalf("should not accept invalid %s: %v", key, value)
+		}
+	}
+
+	raw = testConfig()
+	raw["communicator"] = "winrm"
+	raw["winrm_username"] = "Administrator"
+	raw["initialization_type"] = "sysprep"
+	raw["hostname"] = "windows-build-server"
+	if _, _, errs := NewConfig(raw); errs == nil {
+		t.Fatal("should not accept long hostname with sysprep")
+	}
+	raw["hostname"] = "win-build"
+	raw["timezone"] = "W. Europe Standard Time"
+	if _, _, errs := NewConfig(raw); errs != nil {
+		t.Fatalf("should accept Windows time zone with sysprep: %s", errs)
+	}
+}
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"ovirt_url":            "https://ovirt.example.com/ovirt-engine/api",
+		"username":             "admin@internal",
+		"password":             "password",
+		"ssh_username":         "centos",
+		"source_template_name": "CentOS_7",
+		"address":              "192.168.0.10",
+	}
+}
@@ -0,0 +1,72 @@
+package ovirt
+
+import (
+	"errors"
+	"fmt"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// listDiskAttachments returns the disk attachments of a VM together with
+// the details of the attached disks.
+func listDiskAttachments(conn *ovirtsdk4.Connection, vmID string) ([]*ovirtsdk4.DiskAttachment, error) {
+	resp, err := conn.SystemService().
+		VmsService().
+		VmService(vmID).
+		DiskAttachmentsService().
+		List().
+		Follow("disk").
+		Send()
+	if err != nil {
+		return nil, fmt.Errorf("Error listing disks of VM '%s': %s", vmID, err)
+	}
+
+	if das, ok := resp.Attachments(); ok {
+		return das.Slice(), nil
+	}
+	return nil, nil
+}
+
+// findBootDiskAttachment returns the attachment of the disk which is turned
+// into the artifact. If selector is set, the disk with this name or
+// identifier is chosen, otherwise the bootable disk.
+func findBootDiskAttachment(das []*ovirtsdk4.DiskAttachment, selector string) (*ovirtsdk4.DiskAttachment, error) {
+	if len(das) == 0 {
+		return nil, errors.New("No disks attached to VM")
+	}
+
+	if selector != "" {
+		for _, da := range das {
+			disk, ok := da.Disk()
+			if !ok {
+				continue
+			}
+			if id, ok := disk.Id(); ok && id == selector {
+				return da, nil
+			}
+			if name, ok := disk.Name(); ok && name == selector {
+				return da, nil
+			}
+		}
+		return nil, fmt.Errorf("Could not find disk '%s' attached to VM", selector)
+	}
+
+	var bootDA *ovirtsdk4.DiskAttachment
+	for _, da := range das {
+		if bootable, ok := da.Bootable(); ok && bootable {
+			if bootDA != nil {
+				return nil, errors.New("Found multiple bootable disks attached to VM, set disk_selector to choose one")
+			}
+			bootDA = da
+		}
+	}
+	if bootDA != nil {
+		return bootDA, nil
+	}
+
+	// A single disk is used even if it isn't flagged bootable
+	if len(das) == 1 {
+		return das[0], nil
+	}
+	return nil, errors.New("No bootable disk attached to VM, set disk_selector to choose one")
+}
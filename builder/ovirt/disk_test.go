@@ -0,0 +1,89 @@
+package ovirt
+
+import (
+	"testing"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+func testDiskAttachment(id string, name string, bootable bool) *ovirtsdk4.DiskAttachment {
+	return ovirtsdk4.NewDiskAttachmentBuilder().
+		Id(id).
+		Bootable(bootable).
+		Disk(ovirtsdk4.NewDiskBuilder().
+			Id(id).
+			Name(name).
+			MustBuild()).
+		MustBuild()
+}
+
+func TestFindBootDiskAttachment(t *testing.T) {
+	das := []*ovirtsdk4.DiskAttachment{
+		testDiskAttachment("7a1c6c4e-5d0b-4f8e-8a5b-2f3d4e5f6a7b", "data", false),
+		testDiskAttachment("c2867299-28ea-48a2-922a-805b999fcb2d", "root", true),
+	}
+
+	da, err := findBootDiskAttachment(das, "")
+	if err != nil {
+		t.Fatalf("should find bootable disk: %s", err)
+	}
+	if da.MustDisk().MustName() != "root" {
+		t.Fatalf("should select bootable disk: %s", da.MustDisk().MustName())
+	}
+
+	da, err = findBootDiskAttachment(das, "data")
+	if err != nil {
+		t.Fatalf("should find disk by name: %s", err)
+	}
+	if da.MustDisk().MustName() != "data" {
+		t.Fatalf("should select disk by name: %s", da.MustDisk().MustName())
+	}
+
+	da, err = findBootDiskAttachment(das, "7a1c6c4e-5d0b-4f8e-8a5b-2f3d4e5f6a7b")
+	if err != nil {
+		t.Fatalf("should find disk by id: %s", err)
+	}
+	if da.MustDisk().MustName() != "data" {
+		t.Fatalf("should select disk by id: %s", da.MustDisk().MustName())
+	}
+
+	if _, err := findBootDiskAttachment(das, "swap"); err == nil {
+		t.Fatal("should fail for unknown disk")
+	}
+}
+
+func TestFindBootDiskAttachment_single(t *testing.T) {
+	das := []*ovirtsdk4.DiskAttachment{
+		testDiskAttachment("c2867299-28ea-48a2-922a-805b999fcb2d", "root", false),
+	}
+
+	da, err := findBootDiskAttachment(das, "")
+	if err != nil {
+		t.Fatalf("should use single disk: %s", err)
+	}
+	if da.MustDisk().MustName() != "root" {
+		t.Fatalf("should select single disk: %s", da.MustDisk().MustName())
+	}
+}
+
+func TestFindBootDiskAttachment_errors(t *testing.T) {
+	if _, err := findBootDiskAttachment(nil, ""); err == nil {
+		t.Fatal("should fail without disks")
+	}
+
+	das := []*ovirtsdk4.DiskAttachment{
+		testDiskAttachment("7a1c6c4e-5d0b-4f8e-8a5b-2f3d4e5f6a7b", "data", false),
+		testDiskAttachment("c2867299-28ea-48a2-922a-805b999fcb2d", "root", false),
+	}
+	if _, err := findBootDiskAttachment(das, ""); err == nil {
+		t.Fatal("should fail without bootable disk")
+	}
+
+	das = []*ovirtsdk4.DiskAttachment{
+		testDiskAttachment("7a1c6c4e-5d0b-4f8e-8a5b-2f3d4e5f6a7b", "data", true),
+		testDiskAttachment("c2867299-28ea-48a2-922a-805b999fcb2d", "root", true),
+	}
+	if _, err := findBootDiskAttachment(das, ""); err == nil {
+		t.Fatal("should fail with multiple bootable disks")
+	}
+}
@@ -0,0 +1,55 @@
+package ovirt
+
+import (
+	"log"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// setVMHardware applies the configured hardware overrides to a VM. The
+// operating system settings are merged into osBuilder if given, as some
+// source types also need to set the boot devices.
+func setVMHardware(vmBuilder *ovirtsdk4.VmBuilder, osBuilder *ovirtsdk4.OperatingSystemBuilder, c *Config) {
+	if c.InstanceType != "" {
+		log.Printf("Set instance type: %s", c.InstanceType)
+		vmBuilder.InstanceType(ovirtsdk4.NewInstanceTypeBuilder().
+			Name(c.InstanceType).
+			MustBuild())
+	}
+	if c.Memory > 0 {
+		log.Printf("Set memory: %d MiB", c.Memory)
+		vmBuilder.Memory(int64(c.Memory) * 1024 * 1024)
+	}
+	if c.MaxMemory > 0 {
+		log.Printf("Set maximum memory: %d MiB", c.MaxMemory)
+		vmBuilder.MemoryPolicy(ovirtsdk4.NewMemoryPolicyBuilder().
+			Max(int64(c.MaxMemory) * 1024 * 1024).
+			MustBuild())
+	}
+	if (c.CPUSockets > 0) || (c.CPUCores > 0) || (c.CPUThreads > 0) {
+		log.Printf("Set CPU topology: %d sockets, %d cores, %d threads", c.CPUSockets, c.CPUCores, c.CPUThreads)
+		vmBuilder.Cpu(ovirtsdk4.NewCpuBuilder().
+			Topology(ovirtsdk4.NewCpuTopologyBuilder().
+				Sockets(int64(c.CPUSockets)).
+				Cores(int64(c.CPUCores)).
+				Threads(int64(c.CPUThreads)).
+				MustBuild()).
+			MustBuild())
+	}
+	if c.BiosType != "" {
+		log.Printf("Set BIOS type: %s", c.BiosType)
+		vmBuilder.Bios(ovirtsdk4.NewBiosBuilder().
+			Type(ovirtsdk4.BiosType(c.BiosType)).
+			MustBuild())
+	}
+	if c.OSType != "" {
+		if osBuilder == nil {
+			osBuilder = ovirtsdk4.NewOperatingSystemBuilder()
+		}
+		log.Printf("Set operating system type: %s", c.OSType)
+		osBuilder.Type(c.OSType)
+	}
+	if osBuilder != nil {
+		vmBuilder.Os(osBuilder.MustBuild())
+	}
+}
@@ -0,0 +1,55 @@
+package ovirt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// qcow2Magic is the signature at the beginning of every QCOW2 image
+var qcow2Magic = []byte{'Q', 'F', 'I', 0xfb}
+
+// compressedMagics are the signatures of compressed files which can't be
+// uploaded as disk image
+var compressedMagics = map[string][]byte{
+	"gzip":  {0x1f, 0x8b},
+	"xz":    {0xfd, '7', 'z', 'X', 'Z', 0x00},
+	"bzip2": {'B', 'Z', 'h'},
+}
+
+// imageInfo returns the disk format and the virtual size in bytes of a
+// local image file. Files not recognized as QCOW2 are treated as raw images,
+// compressed files are rejected.
+func imageInfo(path string) (ovirtsdk4.DiskFormat, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("Error opening image '%s': %s", path, err)
+	}
+	defer f.Close()
+
+	// The QCOW2 header stores the virtual size as big-endian integer at
+	// offset 24
+	header := make([]byte, 32)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", 0, fmt.Errorf("Error reading image '%s': %s", path, err)
+	}
+	if (n == len(header)) && bytes.Equal(header[:4], qcow2Magic) {
+		return ovirtsdk4.DISKFORMAT_COW, int64(binary.BigEndian.Uint64(header[24:32])), nil
+	}
+	for compression, magic := range compressedMagics {
+		if bytes.HasPrefix(header[:n], magic) {
+			return "", 0, fmt.Errorf("Image '%s' is %s compressed, decompress it first", path, compression)
+		}
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", 0, fmt.Errorf("Error reading image '%s': %s", path, err)
+	}
+	return ovirtsdk4.DISKFORMAT_RAW, fi.Size(), nil
+}
@@ -0,0 +1,86 @@
+package ovirt
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+func TestImageInfo_qcow2(t *testing.T) {
+	header := make([]byte, 512)
+	copy(header, qcow2Magic)
+	binary.BigEndian.PutUint64(header[24:32], 8*1024*1024*1024)
+	path := testImageFile(t, header)
+	defer os.Remove(path)
+
+	format, size, err := imageInfo(path)
+	if err != nil {
+		t.Fatalf("should not fail to inspect qcow2 image: %s", err)
+	}
+	if format != ovirtsdk4.DISKFORMAT_COW {
+		t.Fatalf("wrong format returned: %s", format)
+	}
+	if size != 8*1024*1024*1024 {
+		t.Fatalf("wrong virtual size returned: %d", size)
+	}
+}
+
+func TestImageInfo_raw(t *testing.T) {
+	path := testImageFile(t, make([]byte, 4096))
+	defer os.Remove(path)
+
+	format, size, err := imageInfo(path)
+	if err != nil {
+		t.Fatalf("should not fail to inspect raw image: %s", err)
+	}
+	if format != ovirtsdk4.DISKFORMAT_RAW {
+		t.Fatalf("wrong format returned: %s", format)
+	}
+	if size != 4096 {
+		t.Fatalf("wrong size returned: %d", size)
+	}
+
+	path = testImageFile(t, []byte("QFI"))
+	defer os.Remove(path)
+	format, _, err = imageInfo(path)
+	if err != nil {
+		t.Fatalf("should not fail to inspect short image: %s", err)
+	}
+	if format != ovirtsdk4.DISKFORMAT_RAW {
+		t.Fatalf("wrong format returned for short image: %s", format)
+	}
+}
+
+func TestImageInfo_compressed(t *testing.T) {
+	for compression, magic := range compressedMagics {
+		content := make([]byte, 512)
+		copy(content, magic)
+		path := testImageFile(t, content)
+		defer os.Remove(path)
+
+		if _, _, err := imageInfo(path); err == nil {
+			t.Fatalf("should fail on %s compressed image", compression)
+		}
+	}
+}
+
+func TestImageInfo_missing(t *testing.T) {
+	if _, _, err := imageInfo("/nonexistent/image.qcow2"); err == nil {
+		t.Fatal("should fail on missing image")
+	}
+}
+
+func testImageFile(t *testing.T, content []byte) string {
+	f, err := ioutil.TempFile("", "packer-ovirt-image")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(content); err != nil {
+		t.Fatalf("failed to write temp file: %s", err)
+	}
+	return f.Name()
+}
@@ -0,0 +1,319 @@
+package ovirt
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// imageTransferChunkSize is the amount of data sent with a single request
+// to the oVirt imageio service.
+const imageTransferChunkSize = 8 * 1024 * 1024
+
+// imageTransferRetries is the number of times an interrupted download is
+// resumed before giving up.
+const imageTransferRetries = 5
+
+// imageTransferRetryDelay is the delay before the first retry of an
+// interrupted download. It grows with every further attempt.
+var imageTransferRetryDelay = 2 * time.Second
+
+// downloadStatusError is returned for unexpected HTTP responses during a
+// download.
+type downloadStatusError struct {
+	resp *http.Response
+}
+
+func (e *downloadStatusError) Error() string {
+	return fmt.Sprintf("unexpected response: %s", e.resp.Status)
+}
+
+// startImageTransfer creates a new image transfer for a disk and waits until
+// it is ready to transfer data.
+func startImageTransfer(conn *ovirtsdk4.Connection, state multistep.StateBag, diskID string, direction ovirtsdk4.ImageTransferDirection, format ovirtsdk4.DiskFormat) (*ovirtsdk4.ImageTransfer, error) {
+	itBuilder := ovirtsdk4.NewImageTransferBuilder().
+		Disk(ovirtsdk4.NewDiskBuilder().
+			Id(diskID).
+			MustBuild()).
+		Direction(direction)
+	if format != "" {
+		itBuilder.Format(format)
+	}
+
+	itAddResp, err := conn.SystemService().
+		ImageTransfersService().
+		Add().
+		ImageTransfer(itBuilder.MustBuild()).
+		Send()
+	if err != nil {
+		return nil, fmt.Errorf("Error creating image transfer for disk '%s': %s", diskID, err)
+	}
+	transferID := itAddResp.MustImageTransfer().MustId()
+	log.Printf("Image transfer id: %s", transferID)
+
+	stateChange := StateChangeConf{
+		Pending:   []string{string(ovirtsdk4.IMAGETRANSFERPHASE_INITIALIZING)},
+		Target:    []string{string(ovirtsdk4.IMAGETRANSFERPHASE_TRANSFERRING)},
+		Refresh:   ImageTransferStateRefreshFunc(conn, transferID),
+		StepState: state,
+	}
+	transfer, err := WaitForState(&stateChange)
+	if err != nil {
+		cancelImageTransfer(conn, transferID)
+		return nil, fmt.Errorf("Failed waiting for image transfer (%s) to become ready: %s", transferID, err)
+	}
+
+	return transfer.(*ovirtsdk4.ImageTransfer), nil
+}
+
+// finalizeImageTransfer completes an image transfer and waits until the
+// disk is unlocked again.
+func finalizeImageTransfer(conn *ovirtsdk4.Connection, state multistep.StateBag, transferID string, diskID string) error {
+	_, err := conn.SystemService().
+		ImageTransfersService().
+		ImageTransferService(transferID).
+		Finalize().
+		Send()
+	if err != nil {
+		return fmt.Errorf("Error finalizing image transfer '%s': %s", transferID, err)
+	}
+
+	stateChange := StateChangeConf{
+		Pending:   []string{string(ovirtsdk4.DISKSTATUS_LOCKED)},
+		Target:    []string{string(ovirtsdk4.DISKSTATUS_OK)},
+		Refresh:   DiskStateRefreshFunc(conn, diskID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		return fmt.Errorf("Failed waiting for disk (%s) to become ok: %s", diskID, err)
+	}
+
+	return nil
+}
+
+// cancelImageTransfer aborts an image transfer. Errors are only logged as
+// this is used on failure paths.
+func cancelImageTransfer(conn *ovirtsdk4.Connection, transferID string) {
+	_, err := conn.SystemService().
+		ImageTransfersService().
+		ImageTransferService(transferID).
+		Cancel().
+		Send()
+	if err != nil {
+		log.Printf("Error cancelling image transfer '%s': %s", transferID, err)
+	}
+}
+
+// imageTransferClient returns a HTTP client used to talk to the oVirt imageio
+// service.
+func imageTransferClient(config *Config) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: config.SkipCertValidation,
+				RootCAs:            config.caPool,
+			},
+		},
+	}
+}
+
+// imageTransferURL returns the URL of the imageio service to use for an
+// image transfer. The host daemon is preferred over the proxy running on the
+// engine if it is reachable.
+func imageTransferURL(client *http.Client, transfer *ovirtsdk4.ImageTransfer) (string, error) {
+	if transferURL, ok := transfer.TransferUrl(); ok && transferURL != "" {
+		req, err := http.NewRequest(http.MethodOptions, transferURL, nil)
+		if err == nil {
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode < 400 {
+					return transferURL, nil
+				}
+			}
+		}
+		log.Printf("Image transfer url '%s' not reachable, trying proxy url", transferURL)
+	}
+
+	if proxyURL, ok := transfer.ProxyUrl(); ok && proxyURL != "" {
+		return proxyURL, nil
+	}
+
+	return "", fmt.Errorf("No usable image transfer url found")
+}
+
+// uploadImage sends the content of a local image file to an imageio
+// service URL.
+func uploadImage(ctx context.Context, ui packer.Ui, client *http.Client, transferURL string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Error opening image '%s': %s", path, err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("Error reading image '%s': %s", path, err)
+	}
+	size := fi.Size()
+
+	stream := ui.TrackProgress(filepath.Base(path), 0, size, f)
+	defer stream.Close()
+
+	for offset := int64(0); offset < size; offset += imageTransferChunkSize {
+		length := int64(imageTransferChunkSize)
+		if offset+length > size {
+			length = size - offset
+		}
+
+		url := transferURL
+		if offset+length == size {
+			// Make sure all data is written to storage with the last request
+			url = fmt.Sprintf("%s?flush=y", transferURL)
+		}
+
+		req, err := http.NewRequest(http.MethodPut, url, io.LimitReader(stream, length))
+		if err != nil {
+			return err
+		}
+		req = req.WithContext(ctx)
+		req.ContentLength = length
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, size))
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("Error uploading image: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("Error uploading image: %s", resp.Status)
+		}
+	}
+
+	return nil
+}
+
+// downloadImage stores the content of an imageio service URL in a local
+// file. Interrupted downloads are resumed with range requests.
+func downloadImage(ctx context.Context, ui packer.Ui, client *http.Client, transferURL string, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("Error creating '%s': %s", path, err)
+	}
+	defer f.Close()
+
+	var offset, size int64
+	for attempt := 0; ; attempt++ {
+		done, err := downloadImageRange(ctx, ui, client, transferURL, f, &offset, &size)
+		if done {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Client errors won't go away by asking again
+		if se, ok := err.(*downloadStatusError); ok && se.resp.StatusCode >= 400 && se.resp.StatusCode < 500 {
+			return fmt.Errorf("Error downloading image: %s", err)
+		}
+		if attempt >= imageTransferRetries {
+			return fmt.Errorf("Error downloading image: %s", err)
+		}
+		log.Printf("Download interrupted at %d bytes, resuming: %s", offset, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * imageTransferRetryDelay):
+		}
+	}
+}
+
+// downloadImageRange requests the image data starting at offset and writes
+// it to f. It returns true once the whole image has been received.
+func downloadImageRange(ctx context.Context, ui packer.Ui, client *http.Client, transferURL string, f *os.File, offset *int64, size *int64) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, transferURL, nil)
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	if *offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", *offset))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The server ignored the range, start over
+		*offset = 0
+		*size = resp.ContentLength
+	case http.StatusPartialContent:
+		if *size <= 0 {
+			*size = *offset + resp.ContentLength
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The previous attempt was interrupted after receiving the last
+		// byte. Otherwise the image changed and has to be fetched again.
+		if (*size > 0) && (*offset == *size) && (contentRangeSize(resp) == *size) {
+			return true, nil
+		}
+		*offset = 0
+		*size = 0
+		return false, fmt.Errorf("range not satisfiable, restarting download")
+	default:
+		return false, &downloadStatusError{resp: resp}
+	}
+
+	if _, err := f.Seek(*offset, io.SeekStart); err != nil {
+		return false, err
+	}
+	if err := f.Truncate(*offset); err != nil {
+		return false, err
+	}
+
+	stream := ui.TrackProgress(filepath.Base(f.Name()), *offset, *size, resp.Body)
+	defer stream.Close()
+
+	n, err := io.Copy(f, stream)
+	*offset += n
+	if err != nil {
+		return false, err
+	}
+	if (*size > 0) && (*offset < *size) {
+		return false, io.ErrUnexpectedEOF
+	}
+
+	return true, nil
+}
+
+// contentRangeSize returns the complete length announced in the
+// Content-Range header of a response or -1 if it is unknown.
+func contentRangeSize(resp *http.Response) int64 {
+	cr := resp.Header.Get("Content-Range")
+	i := strings.LastIndex(cr, "/")
+	if i < 0 {
+		return -1
+	}
+	size, err := strconv.ParseInt(cr[i+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
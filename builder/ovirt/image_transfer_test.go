@@ -0,0 +1,173 @@
+package ovirt
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestDownloadImage(t *testing.T) {
+	content := testImageContent()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "disk", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	path := testDownloadPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	err := downloadImage(context.Background(), new(packer.NoopUi), ts.Client(), ts.URL, path)
+	if err != nil {
+		t.Fatalf("should not fail to download image: %s", err)
+	}
+	testDownloadedContent(t, path, content)
+}
+
+func TestDownloadImage_resume(t *testing.T) {
+	defer func(delay time.Duration) { imageTransferRetryDelay = delay }(imageTransferRetryDelay)
+	imageTransferRetryDelay = time.Millisecond
+
+	content := testImageContent()
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			// Announce the full image but drop the connection half way
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(content[:len(content)/2])
+			panic(http.ErrAbortHandler)
+		}
+		if r.Header.Get("Range") == "" {
+			t.Errorf("should resume with range request")
+		}
+		http.ServeContent(w, r, "disk", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	path := testDownloadPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	err := downloadImage(context.Background(), new(packer.NoopUi), ts.Client(), ts.URL, path)
+	if err != nil {
+		t.Fatalf("should not fail to resume download: %s", err)
+	}
+	if requests != 2 {
+		t.Fatalf("wrong number of requests: %d", requests)
+	}
+	testDownloadedContent(t, path, content)
+}
+
+func TestDownloadImage_error(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer ts.Close()
+
+	path := testDownloadPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	err := downloadImage(context.Background(), new(packer.NoopUi), ts.Client(), ts.URL, path)
+	if err == nil {
+		t.Fatal("should fail on error response")
+	}
+	if requests != 1 {
+		t.Fatalf("should not retry client errors: %d requests", requests)
+	}
+}
+
+func TestDownloadImage_serverError(t *testing.T) {
+	defer func(delay time.Duration) { imageTransferRetryDelay = delay }(imageTransferRetryDelay)
+	imageTransferRetryDelay = time.Millisecond
+
+	content := testImageContent()
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "disk", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	path := testDownloadPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	err := downloadImage(context.Background(), new(packer.NoopUi), ts.Client(), ts.URL, path)
+	if err != nil {
+		t.Fatalf("should retry server errors: %s", err)
+	}
+	testDownloadedContent(t, path, content)
+}
+
+func TestDownloadImageRange_rangeNotSatisfiable(t *testing.T) {
+	content := testImageContent()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "disk", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	path := testDownloadPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create image file: %s", err)
+	}
+	defer f.Close()
+
+	// All data was received before the connection dropped
+	offset, size := int64(len(content)), int64(len(content))
+	done, err := downloadImageRange(context.Background(), new(packer.NoopUi), ts.Client(), ts.URL, f, &offset, &size)
+	if !done || err != nil {
+		t.Fatalf("should finish complete download: %s", err)
+	}
+
+	// The image doesn't match the previous attempt anymore
+	offset, size = int64(2*len(content)), int64(2*len(content))
+	done, err = downloadImageRange(context.Background(), new(packer.NoopUi), ts.Client(), ts.URL, f, &offset, &size)
+	if done || err == nil {
+		t.Fatal("should not finish download of changed image")
+	}
+	if offset != 0 {
+		t.Fatalf("should restart download from the beginning: %d", offset)
+	}
+}
+
+func testImageContent() []byte {
+	content := make([]byte, 1024*1024)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+func testDownloadPath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "packer-ovirt-download")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	return filepath.Join(dir, "disk.qcow2")
+}
+
+func testDownloadedContent(t *testing.T, path string, expected []byte) {
+	result, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read downloaded image: %s", err)
+	}
+	if !bytes.Equal(result, expected) {
+		t.Fatalf("downloaded image differs, got %d bytes", len(result))
+	}
+}
@@ -0,0 +1,323 @@
+package ovirt
+
+import (
+	"fmt"
+	"log"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// findClusterID returns the identifier of the cluster with the given name.
+func findClusterID(conn *ovirtsdk4.Connection, name string) (string, error) {
+	cResp, err := conn.SystemService().
+		ClustersService().
+		List().
+		Send()
+	if err != nil {
+		return "", fmt.Errorf("Error getting cluster list: %s", err)
+	}
+
+	if clusters, ok := cResp.Clusters(); ok {
+		for _, cluster := range clusters.Slice() {
+			if clusterName, ok := cluster.Name(); ok {
+				if clusterName == name {
+					clusterID := cluster.MustId()
+					log.Printf("Using cluster id: %s", clusterID)
+					return clusterID, nil
+				}
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find cluster '%s'", name)
+}
+
+// findTemplateID returns the identifier of the template with the given name
+// and version number.
+func findTemplateID(conn *ovirtsdk4.Connection, name string, version int) (string, error) {
+	log.Printf("Searching for template '%s'", name)
+	tpsResp, err := conn.SystemService().
+		TemplatesService().
+		List().
+		Search(fmt.Sprintf("name=%s", name)).
+		Send()
+	if err != nil {
+		return "", fmt.Errorf("Error searching templates: %s", err)
+	}
+
+	if tpSlice, ok := tpsResp.Templates(); ok {
+		for _, tp := range tpSlice.Slice() {
+			if tp.MustVersion().MustVersionNumber() == int64(version) {
+				return tp.MustId(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find template '%s' with version '%d'", name, version)
+}
+
+// findStorageDomainID returns the identifier of the storage domain with the
+// given name.
+func findStorageDomainID(conn *ovirtsdk4.Connection, name string) (string, error) {
+	sdsResp, err := conn.SystemService().
+		StorageDomainsService().
+		List().
+		Search(fmt.Sprintf("name=%s", name)).
+		Send()
+	if err != nil {
+		return "", fmt.Errorf("Error searching storage domains: %s", err)
+	}
+
+	if sdSlice, ok := sdsResp.StorageDomains(); ok {
+		for _, sd := range sdSlice.Slice() {
+			if sdName, ok := sd.Name(); ok && sdName == name {
+				sdID := sd.MustId()
+				log.Printf("Using storage domain id: %s", sdID)
+				return sdID, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find storage domain '%s'", name)
+}
+
+// findVMID returns the identifier of the virtual machine with the given name.
+func findVMID(conn *ovirtsdk4.Connection, name string) (string, error) {
+	log.Printf("Searching for virtual machine '%s'", name)
+	vmsResp, err := conn.SystemService().
+		VmsService().
+		List().
+		Search(fmt.Sprintf("name=%s", name)).
+		Send()
+	if err != nil {
+		return "", fmt.Errorf("Error searching virtual machines: %s", err)
+	}
+
+	if vmSlice, ok := vmsResp.Vms(); ok {
+		for _, vm := range vmSlice.Slice() {
+			if vmName, ok := vm.Name(); ok && vmName == name {
+				return vm.MustId(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find virtual machine '%s'", name)
+}
+
+// findSnapshotID returns the identifier of the snapshot with the given
+// description of a virtual machine.
+func findSnapshotID(conn *ovirtsdk4.Connection, vmID string, description string) (string, error) {
+	snapsResp, err := conn.SystemService().
+		VmsService().
+		VmService(vmID).
+		SnapshotsService().
+		List().
+		Send()
+	if err != nil {
+		return "", fmt.Errorf("Error listing snapshots of VM '%s': %s", vmID, err)
+	}
+
+	if snapSlice, ok := snapsResp.Snapshots(); ok {
+		for _, snap := range snapSlice.Slice() {
+			if snapDescription, ok := snap.Description(); ok && snapDescription == description {
+				return snap.MustId(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Could not find snapshot '%s' of VM '%s'", description, vmID)
+}
+
+// findDiskID returns the identifier of the disk with the given name.
+func findDiskID(conn *ovirtsdk4.Connection, name string) (string, error) {
+	ids, err := findDiskIDs(conn, name)
+	if err != nil {
+		return "", err
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("Could not find disk '%s'", name)
+	}
+	if len(ids) > 1 {
+		return "", fmt.Errorf("Found multiple disks with name '%s'", name)
+	}
+	return ids[0], nil
+}
+
+// findDiskIDs returns the identifiers of all disks with the given name.
+func findDiskIDs(conn *ovirtsdk4.Connection, name string) ([]string, error) {
+	log.Printf("Searching for disk '%s'", name)
+	disksResp, err := conn.SystemService().
+		DisksService().
+		List().
+		Search(fmt.Sprintf("name=%s", name)).
+		Send()
+	if err != nil {
+		return nil, fmt.Errorf("Error searching disks: %s", err)
+	}
+
+	var ids []string
+	if diskSlice, ok := disksResp.Disks(); ok {
+		for _, disk := range diskSlice.Slice() {
+			if diskName, ok := disk.Name(); ok && diskName == name {
+				ids = append(ids, disk.MustId())
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// findISOFile checks that the ISO file with the given name or identifier is
+// available in an ISO storage domain or was uploaded as disk to a data
+// domain.
+func findISOFile(conn *ovirtsdk4.Connection, name string) error {
+	sdsResp, err := conn.SystemService().
+		StorageDomainsService().
+		List().
+		Search("type=iso").
+		Send()
+	if err != nil {
+		return fmt.Errorf("Error searching storage domains: %s", err)
+	}
+
+	if sdSlice, ok := sdsResp.StorageDomains(); ok {
+		for _, sd := range sdSlice.Slice() {
+			filesResp, err := conn.SystemService().
+				StorageDomainsService().
+				StorageDomainService(sd.MustId()).
+				FilesService().
+				List().
+				Send()
+			if err != nil {
+				// Inactive ISO domains can't be listed
+				log.Printf("Error listing files of storage domain '%s': %s", sd.MustId(), err)
+				continue
+			}
+			if files, ok := filesResp.File(); ok {
+				for _, f := range files.Slice() {
+					id, _ := f.Id()
+					fileName, _ := f.Name()
+					if (id == name) || (fileName == name) {
+						log.Printf("Found ISO file '%s' in storage domain: %s", name, sd.MustId())
+						return nil
+					}
+				}
+			}
+		}
+	}
+
+	if _, err := conn.SystemService().DisksService().DiskService(name).Get().Send(); err == nil {
+		log.Printf("Found ISO file '%s' as disk", name)
+		return nil
+	}
+
+	return fmt.Errorf("Could not find ISO file '%s'", name)
+}
+
+// findDiskProfileID returns the identifier of the disk profile with the given
+// name. If a storage domain is given, only its profiles are considered.
+func findDiskProfileID(conn *ovirtsdk4.Connection, name string, storageDomainID string) (string, error) {
+	dpsResp, err := conn.SystemService().
+		DiskProfilesService().
+		List().
+		Send()
+	if err != nil {
+		return "", fmt.Errorf("Error getting disk profile list: %s", err)
+	}
+
+	if dpSlice, ok := dpsResp.Profile(); ok {
+		for _, dp := range dpSlice.Slice() {
+			if dpName, ok := dp.Name(); !ok || dpName != name {
+				continue
+			}
+			if storageDomainID != "" {
+				if sd, ok := dp.StorageDomain(); !ok || sd.MustId() != storageDomainID {
+					continue
+				}
+			}
+			dpID := dp.MustId()
+			log.Printf("Using disk profile id: %s", dpID)
+			return dpID, nil
+		}
+	}
+
+	return "", fmt.Errorf("Could not find disk profile '%s'", name)
+}
+
+// findNetworkID returns the identifier of the logical network with the given
+// name.
+func findNetworkID(conn *ovirtsdk4.Connection, name string) (string, error) {
+	nsResp, err := conn.SystemService().
+		NetworksService().
+		List().
+		Search(fmt.Sprintf("name=%s", name)).
+		Send()
+	if err != nil {
+		return "", fmt.Errorf("Error searching networks: %s", err)
+	}
+
+	var ids []string
+	if nSlice, ok := nsResp.Networks(); ok {
+		for _, n := range nSlice.Slice() {
+			if nName, ok := n.Name(); ok && nName == name {
+				ids = append(ids, n.MustId())
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("Could not find network '%s'", name)
+	}
+	if len(ids) > 1 {
+		return "", fmt.Errorf("Found multiple networks with name '%s'", name)
+	}
+	log.Printf("Using network id: %s", ids[0])
+	return ids[0], nil
+}
+
+// findVnicProfileID returns the identifier of a vNIC profile. If a network
+// is given, only its profiles are considered and the profile name defaults
+// to the network name.
+func findVnicProfileID(conn *ovirtsdk4.Connection, name string, network string) (string, error) {
+	var networkID string
+	if network != "" {
+		var err error
+		networkID, err = findNetworkID(conn, network)
+		if err != nil {
+			return "", err
+		}
+		if name == "" {
+			name = network
+		}
+	}
+
+	vpsResp, err := conn.SystemService().
+		VnicProfilesService().
+		List().
+		Send()
+	if err != nil {
+		return "", fmt.Errorf("Error getting vNIC profile list: %s", err)
+	}
+
+	var ids []string
+	if vpSlice, ok := vpsResp.Profiles(); ok {
+		for _, vp := range vpSlice.Slice() {
+			if vpName, ok := vp.Name(); !ok || vpName != name {
+				continue
+			}
+			if networkID != "" {
+				if n, ok := vp.Network(); !ok || n.MustId() != networkID {
+					continue
+				}
+			}
+			ids = append(ids, vp.MustId())
+		}
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("Could not find vNIC profile '%s'", name)
+	}
+	if len(ids) > 1 {
+		return "", fmt.Errorf("Found multiple vNIC profiles with name '%s', set the network", name)
+	}
+	log.Printf("Using vNIC profile id: %s", ids[0])
+	return ids[0], nil
+}
@@ -0,0 +1,79 @@
+package ovirt
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// NetworkInterface describes a vNIC of the VM and its configuration inside
+// the guest.
+type NetworkInterface struct {
+	Name         string `mapstructure:"name"`
+	VnicProfile  string `mapstructure:"vnic_profile"`
+	Network      string `mapstructure:"network"`
+	Interface    string `mapstructure:"interface"`
+	MACAddress   string `mapstructure:"mac_address"`
+	BootProtocol string `mapstructure:"boot_protocol"`
+	IPAddress    string `mapstructure:"address"`
+	Netmask      string `mapstructure:"netmask"`
+	Gateway      string `mapstructure:"gateway"`
+}
+
+// Prepare validates the interface settings and fills in the defaults. The
+// guest device name defaults to the given name.
+func (n *NetworkInterface) Prepare(defaultName string) []error {
+	var errs []error
+
+	if n.Name == "" {
+		n.Name = defaultName
+	}
+	if (n.VnicProfile == "") && (n.Network == "") {
+		errs = append(errs, fmt.Errorf("vnic_profile or network must be specified for network interface '%s'", n.Name))
+	}
+	if n.Interface == "" {
+		n.Interface = "virtio"
+	}
+	n.Interface = strings.ToLower(n.Interface)
+	if (n.Interface != "e1000") && (n.Interface != "rtl8139") && (n.Interface != "virtio") {
+		errs = append(errs, fmt.Errorf("Invalid interface of network interface '%s': %s", n.Name, n.Interface))
+	}
+	if n.MACAddress != "" {
+		if _, err := net.ParseMAC(n.MACAddress); err != nil {
+			errs = append(errs, fmt.Errorf("Invalid mac_address of network interface '%s': %s", n.Name, n.MACAddress))
+		}
+	}
+	if n.BootProtocol == "" {
+		n.BootProtocol = "dhcp"
+		if n.IPAddress != "" {
+			n.BootProtocol = "static"
+		}
+	}
+	n.BootProtocol = strings.ToLower(n.BootProtocol)
+	switch n.BootProtocol {
+	case "static":
+		if n.IPAddress == "" {
+			errs = append(errs, fmt.Errorf("address must be specified for static network interface '%s'", n.Name))
+		}
+		if n.Netmask == "" {
+			n.Netmask = "255.255.255.0"
+		}
+		if ip := net.ParseIP(n.IPAddress); (n.IPAddress != "") && ((ip == nil) || (ip.To4() == nil)) {
+			errs = append(errs, fmt.Errorf("Invalid address of network interface '%s': %s", n.Name, n.IPAddress))
+		}
+		if ip := net.ParseIP(n.Netmask); (ip == nil) || (ip.To4() == nil) {
+			errs = append(errs, fmt.Errorf("Invalid netmask of network interface '%s': %s", n.Name, n.Netmask))
+		}
+		if ip := net.ParseIP(n.Gateway); (n.Gateway != "") && ((ip == nil) || (ip.To4() == nil)) {
+			errs = append(errs, fmt.Errorf("Invalid gateway of network interface '%s': %s", n.Name, n.Gateway))
+		}
+	case "dhcp", "none":
+		if (n.IPAddress != "") || (n.Gateway != "") {
+			errs = append(errs, fmt.Errorf("address and gateway of network interface '%s' require boot_protocol static", n.Name))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("Invalid boot_protocol of network interface '%s': %s", n.Name, n.BootProtocol))
+	}
+
+	return errs
+}
@@ -0,0 +1,112 @@
+package ovirt
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// preflightCheck connects to the oVirt API and verifies that all objects
+// referenced by the configuration exist. All problems found are returned
+// together. Remote images of source_image_url are only checked when they
+// are downloaded.
+func preflightCheck(c *Config) error {
+	conn, err := c.connect()
+	if err != nil {
+		return packer.MultiErrorAppend(nil, fmt.Errorf("oVirt: Connection failed, reason: %s", err.Error()))
+	}
+	defer c.disconnect(conn)
+
+	if err := conn.Test(); err != nil {
+		return packer.MultiErrorAppend(nil, fmt.Errorf("oVirt: Could not reach API at %s: %s", c.OvirtURL.String(), err))
+	}
+	if resp, err := conn.SystemService().Get().Send(); err == nil {
+		if pi, ok := resp.MustApi().ProductInfo(); ok {
+			if v, ok := pi.Version(); ok {
+				log.Printf("Connected to %s %s", pi.MustName(), v.MustFullVersion())
+			}
+		}
+	}
+
+	var errs *packer.MultiError
+	if _, err := findClusterID(conn, c.Cluster); err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+	if (c.TemplateCluster != "") && (c.TemplateCluster != c.Cluster) {
+		if _, err := findClusterID(conn, c.TemplateCluster); err != nil {
+			errs = packer.MultiErrorAppend(errs, err)
+		}
+	}
+
+	switch c.SourceType {
+	case "disk":
+		if c.SourceDiskID != "" {
+			_, err = conn.SystemService().DisksService().DiskService(c.SourceDiskID).Get().Send()
+		} else {
+			_, err = findDiskID(conn, c.SourceDiskName)
+		}
+	case "template":
+		if c.SourceTemplateID != "" {
+			_, err = conn.SystemService().TemplatesService().TemplateService(c.SourceTemplateID).Get().Send()
+		} else {
+			_, err = findTemplateID(conn, c.SourceTemplateName, c.SourceTemplateVersion)
+		}
+	case "image":
+		if c.SourceImageFile != "" {
+			_, err = os.Stat(c.SourceImageFile)
+		}
+	case "iso":
+		err = findISOFile(conn, c.SourceISOFile)
+	case "vm":
+		vmID := c.SourceVMID
+		if vmID != "" {
+			_, err = conn.SystemService().VmsService().VmService(vmID).Get().Send()
+		} else {
+			vmID, err = findVMID(conn, c.SourceVMName)
+		}
+		if (err == nil) && (c.SourceSnapshotName != "") {
+			_, err = findSnapshotID(conn, vmID, c.SourceSnapshotName)
+		}
+	}
+	if err != nil {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("Error checking source %s: %s", c.SourceType, err))
+	}
+
+	if c.TemplateBaseName != "" {
+		if _, err := findTemplateID(conn, c.TemplateBaseName, 1); err != nil {
+			errs = packer.MultiErrorAppend(errs, err)
+		}
+	}
+
+	var storageDomainID string
+	if c.StorageDomain != "" {
+		if storageDomainID, err = findStorageDomainID(conn, c.StorageDomain); err != nil {
+			errs = packer.MultiErrorAppend(errs, err)
+		}
+	}
+	if c.DiskProfile != "" {
+		if _, err := findDiskProfileID(conn, c.DiskProfile, storageDomainID); err != nil {
+			errs = packer.MultiErrorAppend(errs, err)
+		}
+	}
+	for _, ad := range c.AdditionalDisks {
+		if ad.StorageDomain == c.StorageDomain {
+			continue
+		}
+		if _, err := findStorageDomainID(conn, ad.StorageDomain); err != nil {
+			errs = packer.MultiErrorAppend(errs, err)
+		}
+	}
+	for _, n := range c.vnicInterfaces() {
+		if _, err := findVnicProfileID(conn, n.VnicProfile, n.Network); err != nil {
+			errs = packer.MultiErrorAppend(errs, err)
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
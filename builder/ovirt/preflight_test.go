@@ -0,0 +1,174 @@
+package ovirt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestPreflightCheck_unreachable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	raw := testConfig()
+	raw["ovirt_url"] = ts.URL + "/ovirt-engine/api"
+	raw["insecure_allow_http"] = true
+	raw["preflight_check"] = true
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize config: %s", errs)
+	}
+
+	err := preflightCheck(c)
+	if err == nil {
+		t.Fatal("should fail for unreachable API")
+	}
+	if _, ok := err.(*packer.MultiError); !ok {
+		t.Fatalf("should return MultiError: %T", err)
+	}
+}
+
+func TestPreflightCheck_multipleErrors(t *testing.T) {
+	api := newTestAPI()
+	defer api.Close()
+
+	// Only the source template exists
+	api.handleXML("/clusters", `<clusters><cluster id="c1"><name>Production</name></cluster></clusters>`)
+	api.handleXML("/templates", `<templates><template id="t1"><name>CentOS_7</name><version><version_number>1</version_number></version></template></templates>`)
+	api.handleXML("/storagedomains", `<storage_domains/>`)
+	api.handleXML("/vnicprofiles", `<vnic_profiles/>`)
+
+	raw := testConfig()
+	delete(raw, "address")
+	raw["ovirt_url"] = api.url()
+	raw["insecure_allow_http"] = true
+	raw["storage_domain"] = "data"
+	raw["network_interfaces"] = []map[string]interface{}{
+		{"vnic_profile": "build"},
+	}
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize config: %s", errs)
+	}
+
+	err := preflightCheck(c)
+	if err == nil {
+		t.Fatal("should fail for missing objects")
+	}
+	merr, ok := err.(*packer.MultiError)
+	if !ok {
+		t.Fatalf("should return MultiError: %T", err)
+	}
+	expected := []string{
+		"Could not find cluster 'Default'",
+		"Could not find storage domain 'data'",
+		"Could not find vNIC profile 'build'",
+	}
+	if len(merr.Errors) != len(expected) {
+		t.Fatalf("should report all problems: %s", err)
+	}
+	for i, e := range merr.Errors {
+		if e.Error() != expected[i] {
+			t.Errorf("wrong error reported: %s", e)
+		}
+	}
+}
+
+func TestPreflightCheck_iso(t *testing.T) {
+	api := newTestAPI()
+	defer api.Close()
+
+	api.handleXML("/clusters", `<clusters><cluster id="c1"><name>Default</name></cluster></clusters>`)
+	api.handle("GET", "/storagedomains", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("search") == "type=iso" {
+			writeXML(w, `<storage_domains><storage_domain id="iso1"><name>ISO</name></storage_domain></storage_domains>`)
+			return
+		}
+		writeXML(w, `<storage_domains><storage_domain id="sd1"><name>data</name></storage_domain></storage_domains>`)
+	})
+	api.handleXML("/storagedomains/iso1/files", `<files><file id="CentOS-7-x86_64-Minimal-1810.iso"><name>CentOS-7-x86_64-Minimal-1810.iso</name></file></files>`)
+	api.handleXML("/vnicprofiles", `<vnic_profiles><vnic_profile id="v1"><name>ovirtmgmt</name><network id="n1"/></vnic_profile></vnic_profiles>`)
+	api.handleXML("/networks", `<networks><network id="n1"><name>ovirtmgmt</name></network></networks>`)
+
+	raw := testConfig()
+	delete(raw, "source_template_name")
+	raw["ovirt_url"] = api.url()
+	raw["insecure_allow_http"] = true
+	raw["source_type"] = "iso"
+	raw["source_iso_file"] = "CentOS-7-x86_64-Minimal-1810.iso"
+	raw["storage_domain"] = "data"
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize config: %s", errs)
+	}
+	if err := preflightCheck(c); err != nil {
+		t.Fatalf("should find ISO file: %s", err)
+	}
+
+	c.SourceISOFile = "Fedora-Server-dvd-x86_64-31-1.9.iso"
+	if err := preflightCheck(c); err == nil {
+		t.Fatal("should fail for missing ISO file")
+	}
+}
+
+func TestPreflightCheck_templateBaseName(t *testing.T) {
+	api := newTestAPI()
+	defer api.Close()
+
+	api.handleXML("/clusters", `<clusters><cluster id="c1"><name>Default</name></cluster></clusters>`)
+	api.handleXML("/templates", `<templates><template id="t1"><name>CentOS_7</name><version><version_number>1</version_number></version></template></templates>`)
+	api.handleXML("/vnicprofiles", `<vnic_profiles><vnic_profile id="v1"><name>ovirtmgmt</name><network id="n1"/></vnic_profile></vnic_profiles>`)
+	api.handleXML("/networks", `<networks><network id="n1"><name>ovirtmgmt</name></network></networks>`)
+
+	raw := testConfig()
+	raw["ovirt_url"] = api.url()
+	raw["insecure_allow_http"] = true
+	raw["template_base_name"] = "CentOS_7"
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize config: %s", errs)
+	}
+	if err := preflightCheck(c); err != nil {
+		t.Fatalf("should find base template: %s", err)
+	}
+
+	api.handleXML("/templates", `<templates/>`)
+	c.SourceTemplateID = "t1"
+	api.handleXML("/templates/t1", `<template id="t1"><name>CentOS_7</name></template>`)
+	if err := preflightCheck(c); err == nil {
+		t.Fatal("should fail for missing base template")
+	}
+}
+
+func TestPreflightCheck_sourceSnapshotName(t *testing.T) {
+	api := newTestAPI()
+	defer api.Close()
+
+	api.handleXML("/clusters", `<clusters><cluster id="c1"><name>Default</name></cluster></clusters>`)
+	api.handleXML("/vms", `<vms><vm id="vm1"><name>centos-base</name></vm></vms>`)
+	api.handleXML("/vms/vm1/snapshots", `<snapshots><snapshot id="s1"><description>clean</description></snapshot></snapshots>`)
+	api.handleXML("/vnicprofiles", `<vnic_profiles><vnic_profile id="v1"><name>ovirtmgmt</name><network id="n1"/></vnic_profile></vnic_profiles>`)
+	api.handleXML("/networks", `<networks><network id="n1"><name>ovirtmgmt</name></network></networks>`)
+
+	raw := testConfig()
+	delete(raw, "source_template_name")
+	raw["ovirt_url"] = api.url()
+	raw["insecure_allow_http"] = true
+	raw["source_type"] = "vm"
+	raw["source_vm_name"] = "centos-base"
+	raw["source_snapshot_name"] = "clean"
+	c, _, errs := NewConfig(raw)
+	if errs != nil {
+		t.Fatalf("should not fail to initialize config: %s", errs)
+	}
+	if err := preflightCheck(c); err != nil {
+		t.Fatalf("should find source snapshot: %s", err)
+	}
+
+	c.SourceSnapshotName = "updated"
+	if err := preflightCheck(c); err == nil {
+		t.Fatal("should fail for missing source snapshot")
+	}
+}
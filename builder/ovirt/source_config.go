@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/packer/template/interpolate"
@@ -19,13 +20,27 @@ type SourceConfig struct {
 	SourceTemplateName    string `mapstructure:"source_template_name"`
 	SourceTemplateVersion int    `mapstructure:"source_template_version"`
 	SourceTemplateID      string `mapstructure:"source_template_id"`
+
+	SourceISOFile string `mapstructure:"source_iso_file"`
+
+	SourceVMName       string `mapstructure:"source_vm_name"`
+	SourceVMID         string `mapstructure:"source_vm_id"`
+	SourceSnapshotName string `mapstructure:"source_snapshot_name"`
+
+	SourceDiskName string `mapstructure:"source_disk_name"`
+	SourceDiskID   string `mapstructure:"source_disk_id"`
+
+	SourceImageURL          string `mapstructure:"source_image_url"`
+	SourceImageFile         string `mapstructure:"source_image_file"`
+	SourceImageChecksum     string `mapstructure:"source_image_checksum"`
+	SourceImageChecksumType string `mapstructure:"source_image_checksum_type"`
 }
 
 // Prepare performs basic validation on the SourceConfig
 func (c *SourceConfig) Prepare(ctx *interpolate.Context) []error {
 	var errs []error
 	// Supported source types must be added in alphabetical order
-	validSourceTypes := []string{"template"}
+	validSourceTypes := []string{"disk", "image", "iso", "template", "vm"}
 
 	if c.Cluster == "" {
 		c.Cluster = "Default"
@@ -36,16 +51,16 @@ func (c *SourceConfig) Prepare(ctx *interpolate.Context) []error {
 		log.Printf("Using default source_type: %s", c.SourceType)
 	}
 	i := sort.SearchStrings(validSourceTypes, c.SourceType)
-	if validSourceTypes[i] != c.SourceType {
+	if (i >= len(validSourceTypes)) || (validSourceTypes[i] != c.SourceType) {
 		errs = append(errs, fmt.Errorf("Invalid source_type: %s", c.SourceType))
 	}
 
-	if (c.SourceType == "template") {
+	if c.SourceType == "template" {
 		if (c.SourceTemplateName != "") && (c.SourceTemplateVersion < 1) {
 			c.SourceTemplateVersion = 1
 			log.Printf("Using default source_template_version: %d", c.SourceTemplateVersion)
 		}
-		if (c.SourceTemplateID != "") {
+		if c.SourceTemplateID != "" {
 			if _, err := uuid.Parse(c.SourceTemplateID); err != nil {
 				errs = append(errs, fmt.Errorf("Invalid source_template_id: %s", c.SourceTemplateID))
 			}
@@ -55,10 +70,64 @@ func (c *SourceConfig) Prepare(ctx *interpolate.Context) []error {
 		}
 	}
 
+	if c.SourceType == "disk" {
+		if c.SourceDiskID != "" {
+			if _, err := uuid.Parse(c.SourceDiskID); err != nil {
+				errs = append(errs, fmt.Errorf("Invalid source_disk_id: %s", c.SourceDiskID))
+			}
+		}
+		if (c.SourceDiskName != "") && (c.SourceDiskID != "") {
+			errs = append(errs, errors.New("Conflict: Set either source_disk_name or source_disk_id"))
+		}
+	}
+	if c.SourceType == "image" {
+		// Supported checksum types must be added in alphabetical order
+		validChecksumTypes := []string{"md5", "none", "sha1", "sha256", "sha512"}
+
+		if c.SourceImageChecksumType == "" {
+			c.SourceImageChecksumType = "sha256"
+			log.Printf("Using default source_image_checksum_type: %s", c.SourceImageChecksumType)
+		}
+		c.SourceImageChecksumType = strings.ToLower(c.SourceImageChecksumType)
+		c.SourceImageChecksum = strings.ToLower(c.SourceImageChecksum)
+		i := sort.SearchStrings(validChecksumTypes, c.SourceImageChecksumType)
+		if (i >= len(validChecksumTypes)) || (validChecksumTypes[i] != c.SourceImageChecksumType) {
+			errs = append(errs, fmt.Errorf("Invalid source_image_checksum_type: %s", c.SourceImageChecksumType))
+		}
+		if (c.SourceImageChecksumType != "none") && (c.SourceImageChecksum == "") {
+			errs = append(errs, errors.New("source_image_checksum must be specified unless source_image_checksum_type is none"))
+		}
+		if (c.SourceImageURL != "") && (c.SourceImageFile != "") {
+			errs = append(errs, errors.New("Conflict: Set either source_image_url or source_image_file"))
+		}
+	}
+	if c.SourceType == "vm" {
+		if c.SourceVMID != "" {
+			if _, err := uuid.Parse(c.SourceVMID); err != nil {
+				errs = append(errs, fmt.Errorf("Invalid source_vm_id: %s", c.SourceVMID))
+			}
+		}
+		if (c.SourceVMName != "") && (c.SourceVMID != "") {
+			errs = append(errs, errors.New("Conflict: Set either source_vm_name or source_vm_id"))
+		}
+	}
+
 	// Required configurations that will display errors if not set
 	if (c.SourceType == "template") && (c.SourceTemplateName == "") && (c.SourceTemplateID == "") {
 		errs = append(errs, errors.New("source_template_name or source_template_id must be specified"))
 	}
+	if (c.SourceType == "disk") && (c.SourceDiskName == "") && (c.SourceDiskID == "") {
+		errs = append(errs, errors.New("source_disk_name or source_disk_id must be specified"))
+	}
+	if (c.SourceType == "image") && (c.SourceImageURL == "") && (c.SourceImageFile == "") {
+		errs = append(errs, errors.New("source_image_url or source_image_file must be specified"))
+	}
+	if (c.SourceType == "vm") && (c.SourceVMName == "") && (c.SourceVMID == "") {
+		errs = append(errs, errors.New("source_vm_name or source_vm_id must be specified"))
+	}
+	if (c.SourceType == "iso") && (c.SourceISOFile == "") {
+		errs = append(errs, errors.New("source_iso_file must be specified"))
+	}
 
 	if len(errs) > 0 {
 		return errs
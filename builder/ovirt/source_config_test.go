@@ -26,6 +26,13 @@ func TestSourceConfig_Prepare_type(t *testing.T) {
 	if errs == nil {
 		t.Fatal("should not accept invalid type")
 	}
+
+	sc = testSourceConfig()
+	sc.SourceType = "zzz"
+	errs = sc.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should not accept type sorted after all valid types")
+	}
 }
 
 func TestSourceConfig_Prepare_template(t *testing.T) {
@@ -74,14 +81,196 @@ func TestSourceConfig_Prepare_template(t *testing.T) {
 }
 
 func testSourceConfig() SourceConfig {
-	return SourceConfig {
+	return SourceConfig{
 		SourceTemplateName: "foo",
 	}
 }
 
 func testTemplateSourceConfig() SourceConfig {
-	return SourceConfig {
-		SourceType: "template",
+	return SourceConfig{
+		SourceType:         "template",
 		SourceTemplateName: "foo",
 	}
 }
+
+func TestSourceConfig_Prepare_iso(t *testing.T) {
+	sc := testISOSourceConfig()
+	errs := sc.Prepare(nil)
+	if errs != nil {
+		t.Fatal("should not fail to initialize iso source config")
+	}
+
+	sc = testISOSourceConfig()
+	sc.SourceISOFile = ""
+	errs = sc.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should not accept empty iso file")
+	}
+}
+
+func testISOSourceConfig() SourceConfig {
+	return SourceConfig{
+		SourceType:    "iso",
+		SourceISOFile: "CentOS-7-x86_64-Minimal-1810.iso",
+	}
+}
+
+func TestSourceConfig_Prepare_vm(t *testing.T) {
+	sc := testVMSourceConfig()
+	errs := sc.Prepare(nil)
+	if errs != nil {
+		t.Fatal("should not fail to initialize vm source config")
+	}
+
+	sc = testVMSourceConfig()
+	sc.SourceSnapshotName = "golden"
+	errs = sc.Prepare(nil)
+	if errs != nil {
+		t.Fatal("should not fail to accept snapshot name")
+	}
+
+	sc = testVMSourceConfig()
+	sc.SourceVMName = ""
+	errs = sc.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should not accept empty vm name")
+	}
+
+	sc = testVMSourceConfig()
+	sc.SourceVMName = ""
+	sc.SourceVMID = "foo"
+	errs = sc.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should not accept invalid vm uuid")
+	}
+
+	sc = testVMSourceConfig()
+	sc.SourceVMName = ""
+	sc.SourceVMID = "c2867299-28ea-48a2-922a-805b999fcb2d"
+	errs = sc.Prepare(nil)
+	if errs != nil {
+		t.Fatal("should not fail when vm id is given")
+	}
+
+	sc = testVMSourceConfig()
+	sc.SourceVMID = "c2867299-28ea-48a2-922a-805b999fcb2d"
+	errs = sc.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should fail when both vm name and id are given")
+	}
+}
+
+func testVMSourceConfig() SourceConfig {
+	return SourceConfig{
+		SourceType:   "vm",
+		SourceVMName: "golden",
+	}
+}
+
+func TestSourceConfig_Prepare_disk(t *testing.T) {
+	sc := testDiskSourceConfig()
+	errs := sc.Prepare(nil)
+	if errs != nil {
+		t.Fatal("should not fail to initialize disk source config")
+	}
+
+	sc = testDiskSourceConfig()
+	sc.SourceDiskName = ""
+	errs = sc.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should not accept empty disk name")
+	}
+
+	sc = testDiskSourceConfig()
+	sc.SourceDiskName = ""
+	sc.SourceDiskID = "foo"
+	errs = sc.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should not accept invalid disk uuid")
+	}
+
+	sc = testDiskSourceConfig()
+	sc.SourceDiskName = ""
+	sc.SourceDiskID = "c2867299-28ea-48a2-922a-805b999fcb2d"
+	errs = sc.Prepare(nil)
+	if errs != nil {
+		t.Fatal("should not fail when disk id is given")
+	}
+
+	sc = testDiskSourceConfig()
+	sc.SourceDiskID = "c2867299-28ea-48a2-922a-805b999fcb2d"
+	errs = sc.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should fail when both disk name and id are given")
+	}
+}
+
+func testDiskSourceConfig() SourceConfig {
+	return SourceConfig{
+		SourceType:     "disk",
+		SourceDiskName: "CentOS_7_Packer",
+	}
+}
+
+func TestSourceConfig_Prepare_image(t *testing.T) {
+	sc := testImageSourceConfig()
+	errs := sc.Prepare(nil)
+	if errs != nil {
+		t.Fatal("should not fail to initialize image source config")
+	}
+	if sc.SourceImageChecksumType != "sha256" {
+		t.Fatalf("should default to sha256 checksum type: %s", sc.SourceImageChecksumType)
+	}
+
+	sc = testImageSourceConfig()
+	sc.SourceImageURL = ""
+	sc.SourceImageFile = "/tmp/CentOS-7-x86_64-GenericCloud.qcow2"
+	errs = sc.Prepare(nil)
+	if errs != nil {
+		t.Fatal("should not fail when image file is given")
+	}
+
+	sc = testImageSourceConfig()
+	sc.SourceImageURL = ""
+	errs = sc.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should not accept empty image url and file")
+	}
+
+	sc = testImageSourceConfig()
+	sc.SourceImageFile = "/tmp/CentOS-7-x86_64-GenericCloud.qcow2"
+	errs = sc.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should fail when both image url and file are given")
+	}
+
+	sc = testImageSourceConfig()
+	sc.SourceImageChecksum = ""
+	errs = sc.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should not accept empty checksum")
+	}
+
+	sc = testImageSourceConfig()
+	sc.SourceImageChecksum = ""
+	sc.SourceImageChecksumType = "none"
+	errs = sc.Prepare(nil)
+	if errs != nil {
+		t.Fatal("should accept empty checksum with checksum type none")
+	}
+
+	sc = testImageSourceConfig()
+	sc.SourceImageChecksumType = "crc32"
+	errs = sc.Prepare(nil)
+	if errs == nil {
+		t.Fatal("should not accept invalid checksum type")
+	}
+}
+
+func testImageSourceConfig() SourceConfig {
+	return SourceConfig{
+		SourceType:          "image",
+		SourceImageURL:      "https://cloud.centos.org/centos/7/images/CentOS-7-x86_64-GenericCloud.qcow2",
+		SourceImageChecksum: "9a5b7bd4e6d4c1ea4bd3bd5ee1a0a2b7b4b9d0c2c6f7a4d1a2e9c7b5f3e1d0a8",
+	}
+}
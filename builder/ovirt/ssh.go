@@ -1,10 +1,32 @@
 package ovirt
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/hashicorp/packer/helper/multistep"
 )
 
 func commHost(state multistep.StateBag) (string, error) {
 	c := state.Get("config").(*Config)
-	return c.IPAddress, nil
+	if c.Comm.Host() != "" {
+		return c.Comm.Host(), nil
+	}
+	if ip := c.staticIPAddress(); ip != "" {
+		return hostAddress(ip), nil
+	}
+	if ip, ok := state.GetOk("ip_address"); ok {
+		return hostAddress(ip.(string)), nil
+	}
+	return "", errors.New("No IP address known for VM")
+}
+
+// hostAddress encloses IPv6 addresses in brackets as the communicators
+// append the port to the host.
+func hostAddress(ip string) string {
+	if strings.Contains(ip, ":") {
+		return fmt.Sprintf("[%s]", ip)
+	}
+	return ip
 }
@@ -0,0 +1,50 @@
+package ovirt
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestCommHost(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &Config{IPAddress: "192.168.0.10"})
+	if host, err := commHost(state); err != nil || host != "192.168.0.10" {
+		t.Fatalf("should return static address: %s (%v)", host, err)
+	}
+
+	state = new(multistep.BasicStateBag)
+	state.Put("config", &Config{})
+	state.Put("ip_address", "192.168.0.20")
+	if host, err := commHost(state); err != nil || host != "192.168.0.20" {
+		t.Fatalf("should return discovered address: %s (%v)", host, err)
+	}
+
+	c := &Config{IPAddress: "192.168.0.10"}
+	c.Comm.Type = "ssh"
+	c.Comm.SSHHost = "build.example.com"
+	state = new(multistep.BasicStateBag)
+	state.Put("config", c)
+	if host, err := commHost(state); err != nil || host != "build.example.com" {
+		t.Fatalf("should return ssh_host: %s (%v)", host, err)
+	}
+
+	state = new(multistep.BasicStateBag)
+	state.Put("config", &Config{IPAddress: "192.168.0.10", IPv6Address: "2001:db8::10"})
+	if host, err := commHost(state); err != nil || host != "[2001:db8::10]" {
+		t.Fatalf("should prefer static IPv6 address: %s (%v)", host, err)
+	}
+
+	state = new(multistep.BasicStateBag)
+	state.Put("config", &Config{})
+	state.Put("ip_address", "2001:db8::20")
+	if host, err := commHost(state); err != nil || host != "[2001:db8::20]" {
+		t.Fatalf("should return discovered IPv6 address: %s (%v)", host, err)
+	}
+
+	state = new(multistep.BasicStateBag)
+	state.Put("config", &Config{})
+	if _, err := commHost(state); err == nil {
+		t.Fatal("should fail without any address")
+	}
+}
@@ -105,6 +105,52 @@ func DiskAttachmentStateRefreshFunc(
 	}
 }
 
+// TemplateStateRefreshFunc returns a StateRefreshFunc that is used to watch
+// a oVirt template.
+func TemplateStateRefreshFunc(
+	conn *ovirtsdk4.Connection, templateID string) StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		resp, err := conn.SystemService().
+			TemplatesService().
+			TemplateService(templateID).
+			Get().
+			Send()
+		if err != nil {
+			if _, ok := err.(*ovirtsdk4.NotFoundError); ok {
+				// Sometimes oVirt has consistency issues and doesn't see
+				// newly created Template instance. Return empty state.
+				return nil, "", nil
+			}
+			return nil, "", err
+		}
+
+		return resp.MustTemplate(), string(resp.MustTemplate().MustStatus()), nil
+	}
+}
+
+// ImageTransferStateRefreshFunc returns a StateRefreshFunc that is used to
+// watch a oVirt image transfer.
+func ImageTransferStateRefreshFunc(
+	conn *ovirtsdk4.Connection, transferID string) StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		resp, err := conn.SystemService().
+			ImageTransfersService().
+			ImageTransferService(transferID).
+			Get().
+			Send()
+		if err != nil {
+			if _, ok := err.(*ovirtsdk4.NotFoundError); ok {
+				// Sometimes oVirt has consistency issues and doesn't see
+				// newly created ImageTransfer instance. Return empty state.
+				return nil, "", nil
+			}
+			return nil, "", err
+		}
+
+		return resp.MustImageTransfer(), string(resp.MustImageTransfer().MustPhase()), nil
+	}
+}
+
 // WaitForState watches an object and waits for it to achieve a certain
 // state.
 func WaitForState(conf *StateChangeConf) (i interface{}, err error) {
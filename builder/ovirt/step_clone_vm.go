@@ -0,0 +1,173 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepCloneVM struct {
+	Debug bool
+	Ctx   interpolate.Context
+}
+
+func (s *stepCloneVM) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+
+	ui.Say("Cloning virtual machine...")
+
+	clusterID, err := findClusterID(conn, config.Cluster)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	state.Put("cluster_id", clusterID)
+
+	sourceVMID := config.SourceVMID
+	if sourceVMID == "" {
+		sourceVMID, err = findVMID(conn, config.SourceVMName)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+	}
+	log.Printf("Using source VM id: %s", sourceVMID)
+
+	var vmID string
+	if config.SourceSnapshotName != "" {
+		snapshotID, err := findSnapshotID(conn, sourceVMID, config.SourceSnapshotName)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		log.Printf("Using snapshot id: %s", snapshotID)
+
+		vmBuilder := ovirtsdk4.NewVmBuilder().
+			Name(config.VMName).
+			Cluster(ovirtsdk4.NewClusterBuilder().
+				Id(clusterID).
+				MustBuild()).
+			SnapshotsOfAny(ovirtsdk4.NewSnapshotBuilder().
+				Id(snapshotID).
+				MustBuild())
+		setVMHardware(vmBuilder, nil, config)
+		vm, err := vmBuilder.Build()
+		if err != nil {
+			err = fmt.Errorf("Error creating VM object: %s", err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+
+		vmAddResp, err := conn.SystemService().
+			VmsService().
+			Add().
+			Clone(true).
+			Vm(vm).
+			Send()
+		if err != nil {
+			err = fmt.Errorf("Error cloning virtual machine: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		newVM, ok := vmAddResp.Vm()
+		if !ok {
+			state.Put("vm_id", "")
+			return multistep.ActionHalt
+		}
+		vmID = newVM.MustId()
+	} else {
+		// Clone the current state of the VM, this doesn't leave anything
+		// behind on the source VM
+		_, err := conn.SystemService().
+			VmsService().
+			VmService(sourceVMID).
+			Clone().
+			Vm(ovirtsdk4.NewVmBuilder().
+				Name(config.VMName).
+				MustBuild()).
+			Send()
+		if err != nil {
+			err = fmt.Errorf("Error cloning virtual machine: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		vmID, err = findVMID(conn, config.VMName)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+	}
+	log.Printf("Virtual machine id: %s", vmID)
+	state.Put("vm_id", vmID)
+
+	ui.Message(fmt.Sprintf("Waiting for VM to become ready (status down) ..."))
+	stateChange := StateChangeConf{
+		Pending:   []string{"image_locked"},
+		Target:    []string{string(ovirtsdk4.VMSTATUS_DOWN)},
+		Refresh:   VMStateRefreshFunc(conn, vmID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		err := fmt.Errorf("Failed waiting for VM (%s) to become down: %s", vmID, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if config.SourceSnapshotName == "" {
+		// The clone action only takes the name, so the cluster and the
+		// hardware are updated afterwards
+		vmBuilder := ovirtsdk4.NewVmBuilder().
+			Cluster(ovirtsdk4.NewClusterBuilder().
+				Id(clusterID).
+				MustBuild())
+		setVMHardware(vmBuilder, nil, config)
+		vm, err := vmBuilder.Build()
+		if err != nil {
+			err = fmt.Errorf("Error creating VM object: %s", err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		if _, err := conn.SystemService().VmsService().VmService(vmID).Update().Vm(vm).Send(); err != nil {
+			err = fmt.Errorf("Error updating virtual machine '%s': %s", vmID, err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCloneVM) Cleanup(state multistep.StateBag) {
+	if _, ok := state.GetOk("vm_id"); !ok {
+		return
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+	vmID := state.Get("vm_id").(string)
+
+	ui.Say(fmt.Sprintf("Deleting virtual machine: %s ...", vmID))
+
+	if _, err := conn.SystemService().VmsService().VmService(vmID).Remove().Send(); err != nil {
+		ui.Error(fmt.Sprintf("Error deleting VM '%s', may still be around: %s", vmID, err))
+	}
+}
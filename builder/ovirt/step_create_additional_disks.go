@@ -0,0 +1,91 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepCreateAdditionalDisks struct{}
+
+func (s *stepCreateAdditionalDisks) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+	vmID := state.Get("vm_id").(string)
+
+	ui.Say("Creating additional disks...")
+
+	for _, ad := range config.AdditionalDisks {
+		storageDomainID, err := findStorageDomainID(conn, ad.StorageDomain)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+
+		diskBuilder := ovirtsdk4.NewDiskBuilder().
+			Name(ad.Name).
+			Format(ovirtsdk4.DiskFormat(ad.Format)).
+			ProvisionedSize(int64(ad.Size) * 1024 * 1024 * 1024).
+			StorageDomainsOfAny(ovirtsdk4.NewStorageDomainBuilder().
+				Id(storageDomainID).
+				MustBuild())
+		if ad.Format == string(ovirtsdk4.DISKFORMAT_COW) {
+			diskBuilder.Sparse(true)
+		}
+		disk, err := diskBuilder.Build()
+		if err != nil {
+			err = fmt.Errorf("Error creating disk object: %s", err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+
+		ui.Message(fmt.Sprintf("Creating disk '%s' with size %d GiB ...", ad.Name, ad.Size))
+		daAddResp, err := conn.SystemService().
+			VmsService().
+			VmService(vmID).
+			DiskAttachmentsService().
+			Add().
+			Attachment(ovirtsdk4.NewDiskAttachmentBuilder().
+				Disk(disk).
+				Interface(ovirtsdk4.DiskInterface(ad.Interface)).
+				Bootable(false).
+				Active(true).
+				MustBuild()).
+			Send()
+		if err != nil {
+			err = fmt.Errorf("Error attaching disk '%s' to VM: %s", ad.Name, err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+
+		diskID := daAddResp.MustAttachment().MustDisk().MustId()
+		log.Printf("Disk identifier: %s", diskID)
+
+		ui.Message(fmt.Sprintf("Waiting for disk '%s' reaching status OK...", diskID))
+		stateChange := StateChangeConf{
+			Pending:   []string{string(ovirtsdk4.DISKSTATUS_LOCKED)},
+			Target:    []string{string(ovirtsdk4.DISKSTATUS_OK)},
+			Refresh:   DiskStateRefreshFunc(conn, diskID),
+			StepState: state,
+		}
+		if _, err := WaitForState(&stateChange); err != nil {
+			err := fmt.Errorf("Failed waiting for disk (%s) to become ok: %s", diskID, err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+// Cleanup does nothing as attached disks are removed together with the VM.
+func (s *stepCreateAdditionalDisks) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,137 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepCreateTemplate struct{}
+
+func (s *stepCreateTemplate) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+	vmID := state.Get("vm_id").(string)
+
+	ui.Say(fmt.Sprintf("Creating template: %s ...", config.TemplateName))
+
+	clusterID, err := findClusterID(conn, config.TemplateCluster)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	tplBuilder := ovirtsdk4.NewTemplateBuilder()
+	if config.TemplateBaseName != "" {
+		baseTemplateID, err := findTemplateID(conn, config.TemplateBaseName, 1)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		log.Printf("Using base template id: %s", baseTemplateID)
+
+		versionBuilder := ovirtsdk4.NewTemplateVersionBuilder().
+			BaseTemplate(ovirtsdk4.NewTemplateBuilder().
+				Id(baseTemplateID).
+				MustBuild())
+		if config.TemplateVersionName != "" {
+			versionBuilder.VersionName(config.TemplateVersionName)
+		}
+		tplBuilder.Version(versionBuilder.MustBuild())
+	}
+	tplBuilder.
+		Name(config.TemplateName).
+		Description(config.TemplateDescription).
+		Cluster(ovirtsdk4.NewClusterBuilder().
+			Id(clusterID).
+			MustBuild()).
+		Vm(ovirtsdk4.NewVmBuilder().
+			Id(vmID).
+			MustBuild())
+
+	t, err := tplBuilder.Build()
+	if err != nil {
+		err = fmt.Errorf("Error creating template object: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	tplAddResp, err := conn.SystemService().
+		TemplatesService().
+		Add().
+		Template(t).
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Error creating template: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	newTemplate, ok := tplAddResp.Template()
+	if !ok {
+		err = fmt.Errorf("Error creating template: no template returned")
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	templateID := newTemplate.MustId()
+	log.Printf("Template id: %s", templateID)
+	state.Put("template_id", templateID)
+
+	ui.Message(fmt.Sprintf("Waiting for template to become ready (status ok) ..."))
+	stateChange := StateChangeConf{
+		Pending:   []string{string(ovirtsdk4.TEMPLATESTATUS_LOCKED)},
+		Target:    []string{string(ovirtsdk4.TEMPLATESTATUS_OK)},
+		Refresh:   TemplateStateRefreshFunc(conn, templateID),
+		StepState: state,
+	}
+	latestTemplate, err := WaitForState(&stateChange)
+	if err != nil {
+		err := fmt.Errorf("Failed waiting for template (%s) to become ok: %s", templateID, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if version, ok := latestTemplate.(*ovirtsdk4.Template).Version(); ok {
+		if versionNumber, ok := version.VersionNumber(); ok {
+			ui.Message(fmt.Sprintf("Template version: %d", versionNumber))
+			state.Put("template_version", versionNumber)
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateTemplate) Cleanup(state multistep.StateBag) {
+	if _, ok := state.GetOk("template_id"); !ok {
+		return
+	}
+
+	// Only remove the template if the build didn't succeed
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+	templateID := state.Get("template_id").(string)
+
+	ui.Say(fmt.Sprintf("Deleting template: %s ...", templateID))
+
+	if _, err := conn.SystemService().TemplatesService().TemplateService(templateID).Remove().Send(); err != nil {
+		ui.Error(fmt.Sprintf("Error deleting template '%s', may still be around: %s", templateID, err))
+	}
+}
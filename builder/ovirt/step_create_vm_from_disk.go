@@ -0,0 +1,247 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepCreateVMFromDisk struct {
+	Debug bool
+	Ctx   interpolate.Context
+
+	diskID       string
+	diskAttached bool
+}
+
+func (s *stepCreateVMFromDisk) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+
+	ui.Say("Creating virtual machine...")
+
+	clusterID, err := findClusterID(conn, config.Cluster)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	state.Put("cluster_id", clusterID)
+
+	var sourceDiskID string
+	if config.SourceDiskID != "" {
+		sourceDiskID = config.SourceDiskID
+	} else {
+		sourceDiskID, err = findDiskID(conn, config.SourceDiskName)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+	}
+	log.Printf("Using source disk id: %s", sourceDiskID)
+
+	diskService := conn.SystemService().
+		DisksService().
+		DiskService(sourceDiskID)
+	dResp, err := diskService.Get().Send()
+	if err != nil {
+		err = fmt.Errorf("Error getting source disk '%s': %s", sourceDiskID, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	// Copy to the storage domain of the source disk if none is given
+	var storageDomainID string
+	if config.StorageDomain != "" {
+		storageDomainID, err = findStorageDomainID(conn, config.StorageDomain)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+	} else if sds, ok := dResp.MustDisk().StorageDomains(); ok && len(sds.Slice()) > 0 {
+		storageDomainID = sds.Slice()[0].MustId()
+	} else {
+		err = fmt.Errorf("Could not determine storage domain of source disk '%s'", sourceDiskID)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	// The copy action doesn't return the new disk. Copy it under a unique
+	// temporary name to find it again and rename it once it is attached.
+	copyName := fmt.Sprintf("%s-%s", config.DiskName, uuid.TimeOrderedUUID())
+
+	ui.Message(fmt.Sprintf("Copying disk: %s ...", sourceDiskID))
+	_, err = diskService.Copy().
+		Disk(ovirtsdk4.NewDiskBuilder().
+			Name(copyName).
+			MustBuild()).
+		StorageDomain(ovirtsdk4.NewStorageDomainBuilder().
+			Id(storageDomainID).
+			MustBuild()).
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Error copying disk '%s': %s", sourceDiskID, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	s.diskID, err = findDiskID(conn, copyName)
+	if err != nil {
+		err = fmt.Errorf("Could not find copy of disk '%s': %s", sourceDiskID, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	log.Printf("Disk identifier: %s", s.diskID)
+
+	ui.Message(fmt.Sprintf("Waiting for disk '%s' reaching status OK...", s.diskID))
+	stateChange := StateChangeConf{
+		Pending:   []string{string(ovirtsdk4.DISKSTATUS_LOCKED)},
+		Target:    []string{string(ovirtsdk4.DISKSTATUS_OK)},
+		Refresh:   DiskStateRefreshFunc(conn, s.diskID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		err := fmt.Errorf("Failed waiting for disk (%s) to become ok: %s", s.diskID, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	vmBuilder := ovirtsdk4.NewVmBuilder().
+		Name(config.VMName).
+		Cluster(ovirtsdk4.NewClusterBuilder().
+			Id(clusterID).
+			MustBuild()).
+		Template(ovirtsdk4.NewTemplateBuilder().
+			Name("Blank").
+			MustBuild())
+	osBuilder := ovirtsdk4.NewOperatingSystemBuilder().
+		Boot(ovirtsdk4.NewBootBuilder().
+			DevicesOfAny(ovirtsdk4.BOOTDEVICE_HD).
+			MustBuild())
+	setVMHardware(vmBuilder, osBuilder, config)
+	vm, err := vmBuilder.Build()
+	if err != nil {
+		err = fmt.Errorf("Error creating VM object: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	vmAddResp, err := conn.SystemService().
+		VmsService().
+		Add().
+		Vm(vm).
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Error creating virtual machine: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	newVM, ok := vmAddResp.Vm()
+	if !ok {
+		state.Put("vm_id", "")
+		return multistep.ActionHalt
+	}
+
+	vmID := newVM.MustId()
+	log.Printf("Virtual machine id: %s", vmID)
+	state.Put("vm_id", vmID)
+
+	ui.Message(fmt.Sprintf("Waiting for VM to become ready (status down) ..."))
+	stateChange = StateChangeConf{
+		Pending:   []string{"image_locked"},
+		Target:    []string{string(ovirtsdk4.VMSTATUS_DOWN)},
+		Refresh:   VMStateRefreshFunc(conn, vmID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		err := fmt.Errorf("Failed waiting for VM (%s) to become down: %s", vmID, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("Attaching disk: %s ...", s.diskID))
+	_, err = conn.SystemService().
+		VmsService().
+		VmService(vmID).
+		DiskAttachmentsService().
+		Add().
+		Attachment(ovirtsdk4.NewDiskAttachmentBuilder().
+			Disk(ovirtsdk4.NewDiskBuilder().
+				Id(s.diskID).
+				MustBuild()).
+			Interface(ovirtsdk4.DISKINTERFACE_VIRTIO).
+			Bootable(true).
+			Active(true).
+			MustBuild()).
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Error attaching disk to VM: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	s.diskAttached = true
+
+	_, err = conn.SystemService().
+		VmsService().
+		VmService(vmID).
+		DiskAttachmentsService().
+		AttachmentService(s.diskID).
+		Update().
+		DiskAttachment(ovirtsdk4.NewDiskAttachmentBuilder().
+			Disk(ovirtsdk4.NewDiskBuilder().
+				Name(config.DiskName).
+				MustBuild()).
+			MustBuild()).
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Error renaming disk '%s': %s", s.diskID, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateVMFromDisk) Cleanup(state multistep.StateBag) {
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+
+	if _, ok := state.GetOk("vm_id"); ok {
+		vmID := state.Get("vm_id").(string)
+
+		ui.Say(fmt.Sprintf("Deleting virtual machine: %s ...", vmID))
+
+		if _, err := conn.SystemService().VmsService().VmService(vmID).Remove().Send(); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting VM '%s', may still be around: %s", vmID, err))
+		}
+	}
+
+	// Attached disks are removed together with the VM
+	if (s.diskID != "") && !s.diskAttached {
+		ui.Say(fmt.Sprintf("Deleting disk: %s ...", s.diskID))
+
+		if _, err := conn.SystemService().DisksService().DiskService(s.diskID).Remove().Send(); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting disk '%s', may still be around: %s", s.diskID, err))
+		}
+	}
+}
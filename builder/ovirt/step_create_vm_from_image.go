@@ -0,0 +1,244 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepCreateVMFromImage struct {
+	Debug bool
+	Ctx   interpolate.Context
+
+	diskID       string
+	diskAttached bool
+}
+
+func (s *stepCreateVMFromImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+	imagePath := state.Get("image_path").(string)
+
+	ui.Say("Creating virtual machine...")
+
+	clusterID, err := findClusterID(conn, config.Cluster)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	state.Put("cluster_id", clusterID)
+
+	storageDomainID, err := findStorageDomainID(conn, config.StorageDomain)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	format, virtualSize, err := imageInfo(imagePath)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	fi, err := os.Stat(imagePath)
+	if err != nil {
+		err = fmt.Errorf("Error reading image '%s': %s", imagePath, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	log.Printf("Image format: %s, virtual size: %d bytes", format, virtualSize)
+
+	diskBuilder := ovirtsdk4.NewDiskBuilder().
+		Name(config.DiskName).
+		Description(config.DiskDescription).
+		Format(format).
+		ProvisionedSize(virtualSize).
+		StorageDomainsOfAny(ovirtsdk4.NewStorageDomainBuilder().
+			Id(storageDomainID).
+			MustBuild())
+	if format == ovirtsdk4.DISKFORMAT_COW {
+		// Block storage domains need to know how much space to allocate
+		// for the QCOW2 image
+		diskBuilder.Sparse(true).InitialSize(fi.Size())
+	}
+	disk, err := diskBuilder.Build()
+	if err != nil {
+		err = fmt.Errorf("Error creating disk object: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	ui.Message("Creating disk ...")
+	diskAddResp, err := conn.SystemService().
+		DisksService().
+		Add().
+		Disk(disk).
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Error creating disk: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	s.diskID = diskAddResp.MustDisk().MustId()
+	log.Printf("Disk identifier: %s", s.diskID)
+
+	ui.Message(fmt.Sprintf("Waiting for disk '%s' reaching status OK...", s.diskID))
+	stateChange := StateChangeConf{
+		Pending:   []string{string(ovirtsdk4.DISKSTATUS_LOCKED)},
+		Target:    []string{string(ovirtsdk4.DISKSTATUS_OK)},
+		Refresh:   DiskStateRefreshFunc(conn, s.diskID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		err := fmt.Errorf("Failed waiting for disk (%s) to become ok: %s", s.diskID, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("Uploading image: %s ...", imagePath))
+	transfer, err := startImageTransfer(conn, state, s.diskID, ovirtsdk4.IMAGETRANSFERDIRECTION_UPLOAD, "")
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	transferID := transfer.MustId()
+
+	client := imageTransferClient(config)
+	transferURL, err := imageTransferURL(client, transfer)
+	if err == nil {
+		err = uploadImage(ctx, ui, client, transferURL, imagePath)
+	}
+	if err != nil {
+		cancelImageTransfer(conn, transferID)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	if err := finalizeImageTransfer(conn, state, transferID, s.diskID); err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	ui.Message("Image successfully uploaded!")
+
+	vmBuilder := ovirtsdk4.NewVmBuilder().
+		Name(config.VMName).
+		Cluster(ovirtsdk4.NewClusterBuilder().
+			Id(clusterID).
+			MustBuild()).
+		Template(ovirtsdk4.NewTemplateBuilder().
+			Name("Blank").
+			MustBuild())
+	osBuilder := ovirtsdk4.NewOperatingSystemBuilder().
+		Boot(ovirtsdk4.NewBootBuilder().
+			DevicesOfAny(ovirtsdk4.BOOTDEVICE_HD).
+			MustBuild())
+	setVMHardware(vmBuilder, osBuilder, config)
+	vm, err := vmBuilder.Build()
+	if err != nil {
+		err = fmt.Errorf("Error creating VM object: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	vmAddResp, err := conn.SystemService().
+		VmsService().
+		Add().
+		Vm(vm).
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Error creating virtual machine: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	newVM, ok := vmAddResp.Vm()
+	if !ok {
+		state.Put("vm_id", "")
+		return multistep.ActionHalt
+	}
+
+	vmID := newVM.MustId()
+	log.Printf("Virtual machine id: %s", vmID)
+	state.Put("vm_id", vmID)
+
+	ui.Message(fmt.Sprintf("Waiting for VM to become ready (status down) ..."))
+	stateChange = StateChangeConf{
+		Pending:   []string{"image_locked"},
+		Target:    []string{string(ovirtsdk4.VMSTATUS_DOWN)},
+		Refresh:   VMStateRefreshFunc(conn, vmID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		err := fmt.Errorf("Failed waiting for VM (%s) to become down: %s", vmID, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("Attaching disk: %s ...", s.diskID))
+	_, err = conn.SystemService().
+		VmsService().
+		VmService(vmID).
+		DiskAttachmentsService().
+		Add().
+		Attachment(ovirtsdk4.NewDiskAttachmentBuilder().
+			Disk(ovirtsdk4.NewDiskBuilder().
+				Id(s.diskID).
+				MustBuild()).
+			Interface(ovirtsdk4.DISKINTERFACE_VIRTIO).
+			Bootable(true).
+			Active(true).
+			MustBuild()).
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Error attaching disk to VM: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	s.diskAttached = true
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateVMFromImage) Cleanup(state multistep.StateBag) {
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+
+	if _, ok := state.GetOk("vm_id"); ok {
+		vmID := state.Get("vm_id").(string)
+
+		ui.Say(fmt.Sprintf("Deleting virtual machine: %s ...", vmID))
+
+		if _, err := conn.SystemService().VmsService().VmService(vmID).Remove().Send(); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting VM '%s', may still be around: %s", vmID, err))
+		}
+	}
+
+	// Attached disks are removed together with the VM
+	if (s.diskID != "") && !s.diskAttached {
+		ui.Say(fmt.Sprintf("Deleting disk: %s ...", s.diskID))
+
+		if _, err := conn.SystemService().DisksService().DiskService(s.diskID).Remove().Send(); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting disk '%s', may still be around: %s", s.diskID, err))
+		}
+	}
+}
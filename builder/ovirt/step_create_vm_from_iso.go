@@ -0,0 +1,223 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepCreateVMFromISO struct {
+	Debug bool
+	Ctx   interpolate.Context
+}
+
+func (s *stepCreateVMFromISO) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+
+	ui.Say("Creating virtual machine...")
+
+	clusterID, err := findClusterID(conn, config.Cluster)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	state.Put("cluster_id", clusterID)
+
+	storageDomainID, err := findStorageDomainID(conn, config.StorageDomain)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	// Boot from the hard disk first so that the installed system is started
+	// once the installer from the CD-ROM has finished
+	vmBuilder := ovirtsdk4.NewVmBuilder().
+		Name(config.VMName).
+		Cluster(ovirtsdk4.NewClusterBuilder().
+			Id(clusterID).
+			MustBuild()).
+		Template(ovirtsdk4.NewTemplateBuilder().
+			Name("Blank").
+			MustBuild())
+	osBuilder := ovirtsdk4.NewOperatingSystemBuilder().
+		Boot(ovirtsdk4.NewBootBuilder().
+			DevicesOfAny(ovirtsdk4.BOOTDEVICE_HD, ovirtsdk4.BOOTDEVICE_CDROM).
+			MustBuild())
+	setVMHardware(vmBuilder, osBuilder, config)
+	vm, err := vmBuilder.Build()
+	if err != nil {
+		err = fmt.Errorf("Error creating VM object: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	vmAddResp, err := conn.SystemService().
+		VmsService().
+		Add().
+		Vm(vm).
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Error creating virtual machine: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	newVM, ok := vmAddResp.Vm()
+	if !ok {
+		state.Put("vm_id", "")
+		return multistep.ActionHalt
+	}
+
+	vmID := newVM.MustId()
+	log.Printf("Virtual machine id: %s", vmID)
+	state.Put("vm_id", vmID)
+
+	ui.Message(fmt.Sprintf("Waiting for VM to become ready (status down) ..."))
+	stateChange := StateChangeConf{
+		Pending:   []string{"image_locked"},
+		Target:    []string{string(ovirtsdk4.VMSTATUS_DOWN)},
+		Refresh:   VMStateRefreshFunc(conn, vmID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		err := fmt.Errorf("Failed waiting for VM (%s) to become down: %s", vmID, err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("Creating disk with size %d GiB ...", config.DiskSize))
+	diskFormat := ovirtsdk4.DISKFORMAT_COW
+	if config.DiskFormat != "" {
+		diskFormat = ovirtsdk4.DiskFormat(config.DiskFormat)
+	}
+	diskBuilder := ovirtsdk4.NewDiskBuilder().
+		Name(config.DiskName).
+		Description(config.DiskDescription).
+		Format(diskFormat).
+		ProvisionedSize(int64(config.DiskSize) * 1024 * 1024 * 1024).
+		StorageDomainsOfAny(ovirtsdk4.NewStorageDomainBuilder().
+			Id(storageDomainID).
+			MustBuild())
+	if sparse := config.DiskSparse.ToBoolPointer(); sparse != nil {
+		diskBuilder.Sparse(*sparse)
+	}
+	if config.DiskProfile != "" {
+		diskProfileID, err := findDiskProfileID(conn, config.DiskProfile, storageDomainID)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		diskBuilder.DiskProfile(ovirtsdk4.NewDiskProfileBuilder().
+			Id(diskProfileID).
+			MustBuild())
+	}
+	disk, err := diskBuilder.Build()
+	if err != nil {
+		err = fmt.Errorf("Error creating disk object: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	daAddResp, err := conn.SystemService().
+		VmsService().
+		VmService(vmID).
+		DiskAttachmentsService().
+		Add().
+		Attachment(ovirtsdk4.NewDiskAttachmentBuilder().
+			Disk(disk).
+			Interface(ovirtsdk4.DISKINTERFACE_VIRTIO).
+			Bootable(true).
+			Active(true).
+			MustBuild()).
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Error attaching disk to VM: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	diskID := daAddResp.MustAttachment().MustDisk().MustId()
+	log.Printf("Disk identifier: %s", diskID)
+
+	ui.Message(fmt.Sprintf("Waiting for disk '%s' reaching status OK...", diskID))
+	stateChange = StateChangeConf{
+		Pending:   []string{string(ovirtsdk4.DISKSTATUS_LOCKED)},
+		Target:    []string{string(ovirtsdk4.DISKSTATUS_OK)},
+		Refresh:   DiskStateRefreshFunc(conn, diskID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		err := fmt.Errorf("Failed waiting for disk (%s) to become ok: %s", diskID, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("Inserting ISO: %s ...", config.SourceISOFile))
+	cdromsService := conn.SystemService().
+		VmsService().
+		VmService(vmID).
+		CdromsService()
+	cdResp, err := cdromsService.List().Send()
+	if err != nil {
+		err = fmt.Errorf("Error listing CD-ROMs of VM: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	cdroms, ok := cdResp.Cdroms()
+	if !ok || len(cdroms.Slice()) == 0 {
+		err = fmt.Errorf("Could not find CD-ROM device of VM '%s'", vmID)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	_, err = cdromsService.CdromService(cdroms.Slice()[0].MustId()).
+		Update().
+		Cdrom(ovirtsdk4.NewCdromBuilder().
+			File(ovirtsdk4.NewFileBuilder().
+				Id(config.SourceISOFile).
+				MustBuild()).
+			MustBuild()).
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Error inserting ISO '%s': %s", config.SourceISOFile, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateVMFromISO) Cleanup(state multistep.StateBag) {
+	if _, ok := state.GetOk("vm_id"); !ok {
+		return
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+	vmID := state.Get("vm_id").(string)
+
+	ui.Say(fmt.Sprintf("Deleting virtual machine: %s ...", vmID))
+
+	if _, err := conn.SystemService().VmsService().VmService(vmID).Remove().Send(); err != nil {
+		ui.Error(fmt.Sprintf("Error deleting VM '%s', may still be around: %s", vmID, err))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 
+	packerconfig "github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
@@ -23,67 +24,27 @@ func (s *stepCreateVMFromTemplate) Run(ctx context.Context, state multistep.Stat
 
 	ui.Say("Creating virtual machine...")
 
-	cResp, err := conn.SystemService().
-		ClustersService().
-		List().
-		Send()
+	clusterID, err := findClusterID(conn, config.Cluster)
 	if err != nil {
-		err := fmt.Errorf("Error getting cluster list: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
-	}
-
-	var clusterID string
-	if clusters, ok := cResp.Clusters(); ok {
-		for _, cluster := range clusters.Slice() {
-			if clusterName, ok := cluster.Name(); ok {
-				if clusterName == config.Cluster {
-					clusterID = cluster.MustId()
-					log.Printf("Using cluster id: %s", clusterID)
-					break
-				}
-			}
-		}
-	}
-	if clusterID == "" {
-		err = fmt.Errorf("Could not find cluster '%s'", config.Cluster)
 		ui.Error(err.Error())
 		state.Put("error", err)
 		return multistep.ActionHalt
 	}
+	state.Put("cluster_id", clusterID)
 
 	var templateID string
 	if config.SourceTemplateID != "" {
 		templateID = config.SourceTemplateID
 	} else {
-		templatesService := conn.SystemService().TemplatesService()
-		log.Printf("Searching for template '%s'", config.SourceTemplateName)
-		tpsResp, err := templatesService.List().
-			Search(fmt.Sprintf("name=%s", config.SourceTemplateName)).
-			Send()
+		templateID, err = findTemplateID(conn, config.SourceTemplateName, config.SourceTemplateVersion)
 		if err != nil {
-			err = fmt.Errorf("Error searching templates: %s", err)
-			ui.Error(err.Error())
-			state.Put("error", err)
-			return multistep.ActionHalt
-		}
-		tpSlice, _ := tpsResp.Templates()
-
-		for _, tp := range tpSlice.Slice() {
-			if tp.MustVersion().MustVersionNumber() == int64(config.SourceTemplateVersion) {
-				templateID = tp.MustId()
-				break
-			}
-		}
-		if templateID == "" {
-			err = fmt.Errorf("Could not find template '%s' with version '%d'", config.SourceTemplateName, config.SourceTemplateVersion)
 			ui.Error(err.Error())
 			state.Put("error", err)
 			return multistep.ActionHalt
 		}
 	}
 	log.Printf("Using template id: %s", templateID)
+	state.Put("source_template_id", templateID)
 
 	vmBuilder := ovirtsdk4.NewVmBuilder().
 		Name(config.VMName)
@@ -109,6 +70,20 @@ func (s *stepCreateVMFromTemplate) Run(ctx context.Context, state multistep.Stat
 		return multistep.ActionHalt
 	}
 	vmBuilder.Template(t)
+	setVMHardware(vmBuilder, nil, config)
+
+	// Disks can only be moved or converted when they are cloned from the
+	// template instead of being thin provisioned on top of it
+	clone := (config.StorageDomain != "") || (config.DiskFormat != "") || (config.DiskSparse != packerconfig.TriUnset) || (config.DiskProfile != "")
+	if clone {
+		das, err := templateDiskAttachments(conn, templateID, config)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		vmBuilder.DiskAttachmentsOfAny(das...)
+	}
 
 	vm, err := vmBuilder.Build()
 	if err != nil {
@@ -121,6 +96,7 @@ func (s *stepCreateVMFromTemplate) Run(ctx context.Context, state multistep.Stat
 	vmAddResp, err := conn.SystemService().
 		VmsService().
 		Add().
+		Clone(clone).
 		Vm(vm).
 		Send()
 	if err != nil {
@@ -163,6 +139,66 @@ func (s *stepCreateVMFromTemplate) Run(ctx context.Context, state multistep.Stat
 	return multistep.ActionContinue
 }
 
+// templateDiskAttachments returns the disk attachments of a template with the
+// configured storage domain, format, allocation policy and profile applied.
+func templateDiskAttachments(conn *ovirtsdk4.Connection, templateID string, config *Config) ([]*ovirtsdk4.DiskAttachment, error) {
+	var storageDomainID, diskProfileID string
+	var err error
+	if config.StorageDomain != "" {
+		storageDomainID, err = findStorageDomainID(conn, config.StorageDomain)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.DiskProfile != "" {
+		diskProfileID, err = findDiskProfileID(conn, config.DiskProfile, storageDomainID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := conn.SystemService().
+		TemplatesService().
+		TemplateService(templateID).
+		DiskAttachmentsService().
+		List().
+		Send()
+	if err != nil {
+		return nil, fmt.Errorf("Error listing disks of template '%s': %s", templateID, err)
+	}
+
+	var das []*ovirtsdk4.DiskAttachment
+	for _, tda := range resp.MustAttachments().Slice() {
+		diskBuilder := ovirtsdk4.NewDiskBuilder().
+			Id(tda.MustDisk().MustId())
+		if storageDomainID != "" {
+			diskBuilder.StorageDomainsOfAny(ovirtsdk4.NewStorageDomainBuilder().
+				Id(storageDomainID).
+				MustBuild())
+		}
+		if config.DiskFormat != "" {
+			diskBuilder.Format(ovirtsdk4.DiskFormat(config.DiskFormat))
+		}
+		if sparse := config.DiskSparse.ToBoolPointer(); sparse != nil {
+			diskBuilder.Sparse(*sparse)
+		}
+		if diskProfileID != "" {
+			diskBuilder.DiskProfile(ovirtsdk4.NewDiskProfileBuilder().
+				Id(diskProfileID).
+				MustBuild())
+		}
+		da, err := ovirtsdk4.NewDiskAttachmentBuilder().
+			Disk(diskBuilder.MustBuild()).
+			Build()
+		if err != nil {
+			return nil, fmt.Errorf("Error creating disk attachment object: %s", err)
+		}
+		das = append(das, da)
+	}
+
+	return das, nil
+}
+
 func (s *stepCreateVMFromTemplate) Cleanup(state multistep.StateBag) {
 	if _, ok := state.GetOk("vm_id"); !ok {
 		return
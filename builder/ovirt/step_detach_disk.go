@@ -10,39 +10,51 @@ import (
 	ovirtsdk4 "github.com/ovirt/go-ovirt"
 )
 
-type stepDetachDisk struct{}
+type stepDetachDisk struct {
+	// detachedIDs are the disks which are no longer removed together
+	// with the VM
+	detachedIDs []string
+}
 
 func (s *stepDetachDisk) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	ui := state.Get("ui").(packer.Ui)
 	conn := state.Get("conn").(*ovirtsdk4.Connection)
 	vmID := state.Get("vm_id").(string)
 
-	ui.Say("Detaching disk from VM ...")
+	ui.Say("Detaching disks from VM ...")
 
-	resp, err := conn.SystemService().
-		VmsService().
-		VmService(vmID).
-		DiskAttachmentsService().
-		List().
-		Send()
+	diskID := state.Get("boot_disk_id").(string)
+	log.Printf("Disk identifier: %s", diskID)
+
+	das, err := listDiskAttachments(conn, vmID)
 	if err != nil {
-		err = fmt.Errorf("Error listing disks of VM: %s", err)
 		ui.Error(err.Error())
 		state.Put("error", err)
 		return multistep.ActionHalt
 	}
-	das := resp.MustAttachments()
 
-	d, _ := conn.FollowLink(das.Slice()[0].MustDisk())
-	disk, ok := d.(*ovirtsdk4.Disk)
-	if !ok {
-		err = fmt.Errorf("Error getting disk of VM: '%s': %s", vmID, err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
+	var diskIDs []string
+	for _, da := range das {
+		id := da.MustDisk().MustId()
+		if err := detachDisk(conn, state, vmID, id); err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		s.detachedIDs = append(s.detachedIDs, id)
+		diskIDs = append(diskIDs, id)
 	}
-	diskID := disk.MustId()
-	log.Printf("Disk identifier: %s", diskID)
+
+	state.Put("disk_id", diskID)
+	state.Put("disk_ids", diskIDs)
+
+	return multistep.ActionContinue
+}
+
+// detachDisk deactivates a disk attachment of a VM and removes it while
+// keeping the disk itself.
+func detachDisk(conn *ovirtsdk4.Connection, state multistep.StateBag, vmID string, diskID string) error {
+	ui := state.Get("ui").(packer.Ui)
 
 	diskAttachmentService := conn.SystemService().
 		VmsService().
@@ -52,10 +64,7 @@ func (s *stepDetachDisk) Run(ctx context.Context, state multistep.StateBag) mult
 
 	dasResp, err := diskAttachmentService.Get().Send()
 	if err != nil {
-		err = fmt.Errorf("Error getting disk attachment '%s': %s", diskID, err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
+		return fmt.Errorf("Error getting disk attachment '%s': %s", diskID, err)
 	}
 
 	if dasResp.MustAttachment().MustActive() {
@@ -67,10 +76,7 @@ func (s *stepDetachDisk) Run(ctx context.Context, state multistep.StateBag) mult
 					MustBuild()).
 			Send()
 		if err != nil {
-			err = fmt.Errorf("Failed to deactivate disk attachment '%s': %s", diskID, err)
-			ui.Error(err.Error())
-			state.Put("error", err)
-			return multistep.ActionHalt
+			return fmt.Errorf("Failed to deactivate disk attachment '%s': %s", diskID, err)
 		}
 	}
 
@@ -81,25 +87,36 @@ func (s *stepDetachDisk) Run(ctx context.Context, state multistep.StateBag) mult
 		Refresh:   DiskAttachmentStateRefreshFunc(conn, vmID, diskID),
 		StepState: state,
 	}
-	_, err = WaitForState(&stateChange)
-	if err != nil {
-		err := fmt.Errorf("Failed waiting for disk attachment (%s) to become inactive: %s", diskID, err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+	if _, err := WaitForState(&stateChange); err != nil {
+		return fmt.Errorf("Failed waiting for disk attachment (%s) to become inactive: %s", diskID, err)
 	}
 
-	_, err = diskAttachmentService.Remove().Send()
-	if err != nil {
-		err := fmt.Errorf("Failed to detach disk (%s) from VM: %s", diskID, err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
+	if _, err := diskAttachmentService.Remove().Send(); err != nil {
+		return fmt.Errorf("Failed to detach disk (%s) from VM: %s", diskID, err)
 	}
 
-	state.Put("disk_id", diskID)
-
-	return multistep.ActionContinue
+	return nil
 }
 
-func (s *stepDetachDisk) Cleanup(state multistep.StateBag) {}
+func (s *stepDetachDisk) Cleanup(state multistep.StateBag) {
+	if len(s.detachedIDs) == 0 {
+		return
+	}
+
+	// Only remove the detached disks if the build didn't succeed
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+
+	for _, diskID := range s.detachedIDs {
+		ui.Say(fmt.Sprintf("Deleting disk: %s ...", diskID))
+		if _, err := conn.SystemService().DisksService().DiskService(diskID).Remove().Send(); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting disk '%s', may still be around: %s", diskID, err))
+		}
+	}
+}
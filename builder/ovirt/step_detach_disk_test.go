@@ -0,0 +1,114 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type testDisk struct {
+	id       string
+	name     string
+	bootable bool
+	active   bool
+}
+
+func (d *testDisk) attachmentXML() string {
+	return fmt.Sprintf(`<disk_attachment id="%s"><active>%t</active><bootable>%t</bootable><disk id="%s"><name>%s</name><status>ok</status></disk></disk_attachment>`,
+		d.id, d.active, d.bootable, d.id, d.name)
+}
+
+// handleDisks registers the disk and disk attachment services of VM vm1
+// for the given disks.
+func (a *testAPI) handleDisks(disks map[string]*testDisk, order []string) {
+	a.handle("GET", "/vms/vm1/diskattachments", func(w http.ResponseWriter, r *http.Request) {
+		body := "<disk_attachments>"
+		for _, id := range order {
+			if d, ok := disks[id]; ok {
+				body += d.attachmentXML()
+			}
+		}
+		writeXML(w, body+"</disk_attachments>")
+	})
+	for _, id := range order {
+		d := disks[id]
+		path := "/vms/vm1/diskattachments/" + d.id
+		a.handle("GET", path, func(w http.ResponseWriter, r *http.Request) {
+			writeXML(w, d.attachmentXML())
+		})
+		a.handle("PUT", path, func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			da, err := ovirtsdk4.XMLDiskAttachmentReadOne(ovirtsdk4.NewXMLReader(body), nil, "")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if active, ok := da.Active(); ok {
+				d.active = active
+			}
+			if disk, ok := da.Disk(); ok {
+				if name, ok := disk.Name(); ok {
+					d.name = name
+				}
+			}
+			writeXML(w, d.attachmentXML())
+		})
+		a.handle("DELETE", path, func(w http.ResponseWriter, r *http.Request) {
+			delete(disks, d.id)
+		})
+		a.handle("GET", "/disks/"+d.id, func(w http.ResponseWriter, r *http.Request) {
+			writeXML(w, fmt.Sprintf(`<disk id="%s"><name>%s</name><status>ok</status></disk>`, d.id, d.name))
+		})
+	}
+}
+
+func TestStepDetachDisk_diskSelector(t *testing.T) {
+	api := newTestAPI()
+	defer api.Close()
+
+	// Both disks are bootable, so the boot disk is chosen by name
+	disks := map[string]*testDisk{
+		"d1": {id: "d1", name: "centos", bootable: true, active: true},
+		"d2": {id: "d2", name: "data", bootable: true, active: true},
+	}
+	api.handleDisks(disks, []string{"d1", "d2"})
+
+	conn := api.connect(t)
+	defer conn.Close()
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &Config{
+		DiskName:     "packer-centos",
+		DiskSelector: "centos",
+	})
+	state.Put("conn", conn)
+	state.Put("ui", packer.TestUi(t))
+	state.Put("vm_id", "vm1")
+
+	steps := []multistep.Step{
+		&stepFindBootDisk{},
+		&stepUpdateDisk{},
+		&stepDetachDisk{},
+	}
+	for _, step := range steps {
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("%T should not halt: %s", step, state.Get("error"))
+		}
+	}
+
+	if diskID := state.Get("disk_id"); diskID != "d1" {
+		t.Fatalf("wrong disk detached as boot disk: %v", diskID)
+	}
+	if diskName := state.Get("disk_name"); diskName != "packer-centos" {
+		t.Fatalf("should rename boot disk: %v", diskName)
+	}
+	if len(disks) != 0 {
+		t.Fatalf("should detach all disks: %v", disks)
+	}
+}
@@ -0,0 +1,81 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepDownloadDisk struct{}
+
+func (s *stepDownloadDisk) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+	diskID := state.Get("disk_id").(string)
+
+	// oVirt doesn't convert the disk during the download
+	if diskFormat, ok := state.GetOk("disk_format"); ok && (exportFormat(diskFormat.(string)) != config.ExportFormat) {
+		err := fmt.Errorf("Disk '%s' has format %s and can't be exported as %s", diskID, diskFormat, config.ExportFormat)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Downloading disk to: %s ...", config.ExportLocalPath))
+
+	format := ovirtsdk4.DISKFORMAT_COW
+	if config.ExportFormat == "raw" {
+		format = ovirtsdk4.DISKFORMAT_RAW
+	}
+
+	transfer, err := startImageTransfer(conn, state, diskID, ovirtsdk4.IMAGETRANSFERDIRECTION_DOWNLOAD, format)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	transferID := transfer.MustId()
+
+	client := imageTransferClient(config)
+	transferURL, err := imageTransferURL(client, transfer)
+	if err == nil {
+		err = downloadImage(ctx, ui, client, transferURL, config.ExportLocalPath)
+	}
+	if err != nil {
+		cancelImageTransfer(conn, transferID)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	if err := finalizeImageTransfer(conn, state, transferID, diskID); err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	ui.Message("Disk successfully downloaded!")
+
+	state.Put("export_path", config.ExportLocalPath)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepDownloadDisk) Cleanup(state multistep.StateBag) {
+	// Only remove the downloaded file if the build didn't succeed
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	config := state.Get("config").(*Config)
+	if err := os.Remove(config.ExportLocalPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error removing '%s': %s", config.ExportLocalPath, err)
+	}
+}
@@ -0,0 +1,56 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepEjectCdrom struct{}
+
+func (s *stepEjectCdrom) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+	vmID := state.Get("vm_id").(string)
+
+	ui.Say("Ejecting CD-ROM ...")
+
+	cdromsService := conn.SystemService().
+		VmsService().
+		VmService(vmID).
+		CdromsService()
+	cdResp, err := cdromsService.List().Send()
+	if err != nil {
+		err = fmt.Errorf("Error listing CD-ROMs of VM: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	if cdroms, ok := cdResp.Cdroms(); ok {
+		for _, cdrom := range cdroms.Slice() {
+			// An empty file identifier ejects the medium
+			_, err := cdromsService.CdromService(cdrom.MustId()).
+				Update().
+				Cdrom(ovirtsdk4.NewCdromBuilder().
+					File(ovirtsdk4.NewFileBuilder().
+						Id("").
+						MustBuild()).
+					MustBuild()).
+				Send()
+			if err != nil {
+				err = fmt.Errorf("Error ejecting CD-ROM: %s", err)
+				ui.Error(err.Error())
+				state.Put("error", err)
+				return multistep.ActionHalt
+			}
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepEjectCdrom) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,42 @@
+package ovirt
+
+import (
+	"context"
+	"log"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+// stepFindBootDisk resolves the disk which is turned into the artifact
+// right after the VM was created. The later steps use the identifier as the
+// disk name and the bootable flags may change during the build.
+type stepFindBootDisk struct{}
+
+func (s *stepFindBootDisk) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+	vmID := state.Get("vm_id").(string)
+
+	das, err := listDiskAttachments(conn, vmID)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	bootDA, err := findBootDiskAttachment(das, config.DiskSelector)
+	if err != nil {
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	diskID := bootDA.MustDisk().MustId()
+	log.Printf("Boot disk identifier: %s", diskID)
+	state.Put("boot_disk_id", diskID)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepFindBootDisk) Cleanup(state multistep.StateBag) {}
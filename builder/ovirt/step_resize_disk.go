@@ -0,0 +1,85 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepResizeDisk struct{}
+
+func (s *stepResizeDisk) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+	vmID := state.Get("vm_id").(string)
+
+	diskID := state.Get("boot_disk_id").(string)
+
+	dResp, err := conn.SystemService().
+		DisksService().
+		DiskService(diskID).
+		Get().
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Error getting disk '%s': %s", diskID, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	currentSize := dResp.MustDisk().MustProvisionedSize()
+	requestedSize := int64(config.DiskSize) * 1024 * 1024 * 1024
+	if requestedSize < currentSize {
+		err = fmt.Errorf("Requested disk_size %d GiB is smaller than the current disk size of %d bytes", config.DiskSize, currentSize)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	if requestedSize == currentSize {
+		log.Printf("Disk '%s' already has the requested size", diskID)
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Resizing disk to %d GiB ...", config.DiskSize))
+	_, err = conn.SystemService().
+		VmsService().
+		VmService(vmID).
+		DiskAttachmentsService().
+		AttachmentService(diskID).
+		Update().
+		DiskAttachment(ovirtsdk4.NewDiskAttachmentBuilder().
+			Disk(ovirtsdk4.NewDiskBuilder().
+				ProvisionedSize(requestedSize).
+				MustBuild()).
+			MustBuild()).
+		Send()
+	if err != nil {
+		err = fmt.Errorf("Failed to resize disk '%s': %s", diskID, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("Waiting for disk '%s' reaching status OK...", diskID))
+	stateChange := StateChangeConf{
+		Pending:   []string{string(ovirtsdk4.DISKSTATUS_LOCKED)},
+		Target:    []string{string(ovirtsdk4.DISKSTATUS_OK)},
+		Refresh:   DiskStateRefreshFunc(conn, diskID),
+		StepState: state,
+	}
+	if _, err := WaitForState(&stateChange); err != nil {
+		err := fmt.Errorf("Failed waiting for disk (%s) to become ok: %s", diskID, err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepResizeDisk) Cleanup(state multistep.StateBag) {}
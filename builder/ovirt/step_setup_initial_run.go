@@ -3,17 +3,22 @@ package ovirt
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"strconv"
+	"strings"
 
 	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
 	ovirtsdk4 "github.com/ovirt/go-ovirt"
 )
 
 type stepSetupInitialRun struct {
 	Debug bool
 	Comm  *communicator.Config
+	Ctx   interpolate.Context
 }
 
 // Run executes the Packer build step that configures the initial run setup
@@ -30,7 +35,62 @@ func (s *stepSetupInitialRun) Run(ctx context.Context, state multistep.StateBag)
 		VmService(vmID)
 
 	initializationBuilder := ovirtsdk4.NewInitializationBuilder()
-	if s.Comm.SSHUsername != "" {
+	if c.InitializationType == "sysprep" {
+		if c.SysprepFile != "" {
+			content, err := ioutil.ReadFile(c.SysprepFile)
+			if err != nil {
+				err = fmt.Errorf("Error reading sysprep_file: %s", err)
+				ui.Error(err.Error())
+				state.Put("error", err)
+				return multistep.ActionHalt
+			}
+			unattend, err := interpolate.Render(string(content), &s.Ctx)
+			if err != nil {
+				err = fmt.Errorf("Error rendering sysprep_file: %s", err)
+				ui.Error(err.Error())
+				state.Put("error", err)
+				return multistep.ActionHalt
+			}
+			log.Printf("Set sysprep answer file from: %s", c.SysprepFile)
+			initializationBuilder.CustomScript(unattend)
+		}
+		if c.SysprepAdminPassword != "" {
+			initializationBuilder.RootPassword(c.SysprepAdminPassword)
+		}
+		if c.SysprepDomain != "" {
+			log.Printf("Set domain: %s", c.SysprepDomain)
+			initializationBuilder.Domain(c.SysprepDomain)
+		}
+		if c.SysprepDomainOU != "" {
+			log.Printf("Set active directory OU: %s", c.SysprepDomainOU)
+			initializationBuilder.ActiveDirectoryOu(c.SysprepDomainOU)
+		}
+	} else {
+		// The custom script is merged into the cloud-config generated by
+		// oVirt, so it may be used for anything cloud-init supports
+		userData := c.UserData
+		if c.UserDataFile != "" {
+			content, err := ioutil.ReadFile(c.UserDataFile)
+			if err != nil {
+				err = fmt.Errorf("Error reading user_data_file: %s", err)
+				ui.Error(err.Error())
+				state.Put("error", err)
+				return multistep.ActionHalt
+			}
+			userData, err = interpolate.Render(string(content), &s.Ctx)
+			if err != nil {
+				err = fmt.Errorf("Error rendering user_data_file: %s", err)
+				ui.Error(err.Error())
+				state.Put("error", err)
+				return multistep.ActionHalt
+			}
+			log.Printf("Set cloud-init user data from: %s", c.UserDataFile)
+		}
+		if userData != "" {
+			initializationBuilder.CustomScript(userData)
+		}
+	}
+	if (s.Comm.Type == "ssh") && (s.Comm.SSHUsername != "") {
 		log.Printf("Set SSH user name: %s", s.Comm.SSHUsername)
 		initializationBuilder.UserName(s.Comm.SSHUsername)
 	}
@@ -39,18 +99,54 @@ func (s *stepSetupInitialRun) Run(ctx context.Context, state multistep.StateBag)
 		log.Printf("Set authorized SSH key: %s", string(publicKey))
 		initializationBuilder.AuthorizedSshKeys(string(publicKey))
 	}
-	if c.IPAddress != "" {
+	if c.Hostname != "" {
+		log.Printf("Set host name: %s", c.Hostname)
+		initializationBuilder.HostName(c.Hostname)
+	}
+	if c.Timezone != "" {
+		log.Printf("Set timezone: %s", c.Timezone)
+		initializationBuilder.Timezone(c.Timezone)
+	}
+	if len(c.DNSServers) > 0 {
+		log.Printf("Set DNS servers: %s", strings.Join(c.DNSServers, ", "))
+		initializationBuilder.DnsServers(strings.Join(c.DNSServers, " "))
+	}
+	if len(c.DNSSearch) > 0 {
+		log.Printf("Set DNS search domains: %s", strings.Join(c.DNSSearch, ", "))
+		initializationBuilder.DnsSearch(strings.Join(c.DNSSearch, " "))
+	}
+	if (c.IPAddress != "") || (c.IPv6BootProtocol != "") {
 		ncBuilder := ovirtsdk4.NewNicConfigurationBuilder().
 			Name("eth0").
-			BootProtocol(ovirtsdk4.BootProtocol("static")).
 			OnBoot(true)
-		log.Printf("Set static IP address: %s/%s", c.IPAddress, c.Netmask)
-		log.Printf("Set gateway: %s", c.Gateway)
-		ipBuilder := ovirtsdk4.NewIpBuilder().
-			Address(c.IPAddress).
-			Netmask(c.Netmask).
-			Gateway(c.Gateway)
-		nc, err := ncBuilder.IpBuilder(ipBuilder).Build()
+		if c.IPAddress != "" {
+			log.Printf("Set static IP address: %s/%s", c.IPAddress, c.Netmask)
+			log.Printf("Set gateway: %s", c.Gateway)
+			ncBuilder.BootProtocol(ovirtsdk4.BOOTPROTOCOL_STATIC).
+				IpBuilder(ovirtsdk4.NewIpBuilder().
+					Address(c.IPAddress).
+					Netmask(c.Netmask).
+					Gateway(c.Gateway))
+		} else {
+			ncBuilder.BootProtocol(ovirtsdk4.BOOTPROTOCOL_DHCP)
+		}
+		if c.IPv6BootProtocol != "" {
+			log.Printf("Set IPv6 boot protocol: %s", c.IPv6BootProtocol)
+			ncBuilder.Ipv6BootProtocol(ovirtsdk4.BootProtocol(c.IPv6BootProtocol))
+		}
+		if c.IPv6Address != "" {
+			log.Printf("Set static IPv6 address: %s/%d", c.IPv6Address, c.IPv6Prefix)
+			ipv6Builder := ovirtsdk4.NewIpBuilder().
+				Version(ovirtsdk4.IPVERSION_V6).
+				Address(c.IPv6Address).
+				Netmask(strconv.Itoa(c.IPv6Prefix))
+			if c.IPv6Gateway != "" {
+				log.Printf("Set IPv6 gateway: %s", c.IPv6Gateway)
+				ipv6Builder.Gateway(c.IPv6Gateway)
+			}
+			ncBuilder.Ipv6Builder(ipv6Builder)
+		}
+		nc, err := ncBuilder.Build()
 		if err != nil {
 			err = fmt.Errorf("Error setting NIC configuration: %s", err)
 			ui.Error(err.Error())
@@ -59,6 +155,31 @@ func (s *stepSetupInitialRun) Run(ctx context.Context, state multistep.StateBag)
 		}
 		initializationBuilder.NicConfigurationsOfAny(nc)
 	}
+	for _, n := range c.NetworkInterfaces {
+		ncBuilder := ovirtsdk4.NewNicConfigurationBuilder().
+			Name(n.Name).
+			BootProtocol(ovirtsdk4.BootProtocol(n.BootProtocol)).
+			OnBoot(true)
+		if n.BootProtocol == "static" {
+			log.Printf("Set static IP address of %s: %s/%s", n.Name, n.IPAddress, n.Netmask)
+			ipBuilder := ovirtsdk4.NewIpBuilder().
+				Address(n.IPAddress).
+				Netmask(n.Netmask)
+			if n.Gateway != "" {
+				log.Printf("Set gateway of %s: %s", n.Name, n.Gateway)
+				ipBuilder.Gateway(n.Gateway)
+			}
+			ncBuilder.IpBuilder(ipBuilder)
+		}
+		nc, err := ncBuilder.Build()
+		if err != nil {
+			err = fmt.Errorf("Error setting NIC configuration of %s: %s", n.Name, err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		initializationBuilder.NicConfigurationsOfAny(nc)
+	}
 
 	initialization, err := initializationBuilder.Build()
 	if err != nil {
@@ -79,10 +200,13 @@ func (s *stepSetupInitialRun) Run(ctx context.Context, state multistep.StateBag)
 
 	ui.Say("Starting virtual machine...")
 
-	_, err = vmService.Start().
-		UseCloudInit(true).
-		Vm(vm).
-		Send()
+	startRequest := vmService.Start().Vm(vm)
+	if c.InitializationType == "sysprep" {
+		startRequest.UseSysprep(true)
+	} else {
+		startRequest.UseCloudInit(true)
+	}
+	_, err = startRequest.Send()
 	if err != nil {
 		err = fmt.Errorf("Error starting VM: %s", err)
 		ui.Error(err.Error())
@@ -0,0 +1,90 @@
+package ovirt
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepSetupNetworkInterfaces struct{}
+
+func (s *stepSetupNetworkInterfaces) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+	vmID := state.Get("vm_id").(string)
+
+	ui.Say("Setting up network interfaces...")
+
+	nicsService := conn.SystemService().
+		VmsService().
+		VmService(vmID).
+		NicsService()
+
+	// Replace the NICs inherited from the source
+	resp, err := nicsService.List().Send()
+	if err != nil {
+		err = fmt.Errorf("Error listing network interfaces of VM: %s", err)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+	if nics, ok := resp.Nics(); ok {
+		for _, nic := range nics.Slice() {
+			nicID := nic.MustId()
+			log.Printf("Removing network interface: %s", nicID)
+			if _, err := nicsService.NicService(nicID).Remove().Send(); err != nil {
+				err = fmt.Errorf("Error removing network interface '%s': %s", nicID, err)
+				ui.Error(err.Error())
+				state.Put("error", err)
+				return multistep.ActionHalt
+			}
+		}
+	}
+
+	for i, n := range config.vnicInterfaces() {
+		vnicProfileID, err := findVnicProfileID(conn, n.VnicProfile, n.Network)
+		if err != nil {
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+
+		nicName := fmt.Sprintf("nic%d", i+1)
+		nicBuilder := ovirtsdk4.NewNicBuilder().
+			Name(nicName).
+			Interface(ovirtsdk4.NicInterface(n.Interface)).
+			VnicProfile(ovirtsdk4.NewVnicProfileBuilder().
+				Id(vnicProfileID).
+				MustBuild())
+		if n.MACAddress != "" {
+			nicBuilder.Mac(ovirtsdk4.NewMacBuilder().
+				Address(n.MACAddress).
+				MustBuild())
+		}
+		nic, err := nicBuilder.Build()
+		if err != nil {
+			err = fmt.Errorf("Error creating network interface object: %s", err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+
+		ui.Message(fmt.Sprintf("Adding network interface '%s' for guest device %s ...", nicName, n.Name))
+		if _, err := nicsService.Add().Nic(nic).Send(); err != nil {
+			err = fmt.Errorf("Error adding network interface '%s': %s", nicName, err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+// Cleanup does nothing as the NICs are removed together with the VM.
+func (s *stepSetupNetworkInterfaces) Cleanup(state multistep.StateBag) {}
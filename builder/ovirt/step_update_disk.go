@@ -20,30 +20,21 @@ func (s *stepUpdateDisk) Run(ctx context.Context, state multistep.StateBag) mult
 
 	ui.Say("Updating disk properties ...")
 
-	resp, err := conn.SystemService().
-		VmsService().
-		VmService(vmID).
-		DiskAttachmentsService().
-		List().
-		Send()
+	diskID := state.Get("boot_disk_id").(string)
+	log.Printf("Disk identifier: %s", diskID)
+
+	das, err := listDiskAttachments(conn, vmID)
 	if err != nil {
-		err = fmt.Errorf("Error listing disks of VM: %s", err)
 		ui.Error(err.Error())
 		state.Put("error", err)
 		return multistep.ActionHalt
 	}
-	das := resp.MustAttachments()
 
-	d, _ := conn.FollowLink(das.Slice()[0].MustDisk())
-	disk, ok := d.(*ovirtsdk4.Disk)
-	if !ok {
-		err = fmt.Errorf("Error getting disk of VM: '%s': %s", vmID, err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
+	// Only the boot disk is renamed, but all disks need to be ready
+	var diskIDs []string
+	for _, da := range das {
+		diskIDs = append(diskIDs, da.MustDisk().MustId())
 	}
-	diskID := disk.MustId()
-	log.Printf("Disk identifier: %s", diskID)
 
 	diskAttachmentService := conn.SystemService().
 		VmsService().
@@ -78,19 +69,60 @@ func (s *stepUpdateDisk) Run(ctx context.Context, state multistep.StateBag) mult
 		return multistep.ActionHalt
 	}
 
-	ui.Message(fmt.Sprintf("Waiting for disk '%s' reaching status OK...", diskID))
-	stateChange := StateChangeConf{
-		Pending:   []string{string(ovirtsdk4.DISKSTATUS_LOCKED)},
-		Target:    []string{string(ovirtsdk4.DISKSTATUS_OK)},
-		Refresh:   DiskStateRefreshFunc(conn, diskID),
-		StepState: state,
+	var disk *ovirtsdk4.Disk
+	for _, id := range diskIDs {
+		ui.Message(fmt.Sprintf("Waiting for disk '%s' reaching status OK...", id))
+		stateChange := StateChangeConf{
+			Pending:   []string{string(ovirtsdk4.DISKSTATUS_LOCKED)},
+			Target:    []string{string(ovirtsdk4.DISKSTATUS_OK)},
+			Refresh:   DiskStateRefreshFunc(conn, id),
+			StepState: state,
+		}
+		latestDisk, err := WaitForState(&stateChange)
+		if err != nil {
+			err := fmt.Errorf("Failed waiting for disk (%s) to become ok: %s", id, err)
+			ui.Error(err.Error())
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		if id == diskID {
+			disk = latestDisk.(*ovirtsdk4.Disk)
+		}
 	}
-	_, err = WaitForState(&stateChange)
-	if err != nil {
-		err := fmt.Errorf("Failed waiting for disk attachment (%s) to become inactive: %s", diskID, err)
-		ui.Error(err.Error())
-		state.Put("error", err)
-		return multistep.ActionHalt
+
+	// Remember disk details for the artifact
+	if name, ok := disk.Name(); ok {
+		state.Put("disk_name", name)
+	}
+	if size, ok := disk.ProvisionedSize(); ok {
+		state.Put("provisioned_size", size)
+	}
+	if size, ok := disk.ActualSize(); ok {
+		state.Put("actual_size", size)
+	}
+	if format, ok := disk.Format(); ok {
+		state.Put("disk_format", string(format))
+	}
+	if sparse, ok := disk.Sparse(); ok {
+		state.Put("disk_sparse", sparse)
+	}
+	if dp, ok := disk.DiskProfile(); ok {
+		if link, err := conn.FollowLink(dp); err != nil {
+			log.Printf("Error getting disk profile of disk '%s': %s", diskID, err)
+		} else if dp, ok := link.(*ovirtsdk4.DiskProfile); !ok {
+			log.Printf("Unexpected disk profile of disk '%s': %T", diskID, link)
+		} else if name, ok := dp.Name(); ok {
+			state.Put("disk_profile", name)
+		}
+	}
+	if sds, ok := disk.StorageDomains(); ok && len(sds.Slice()) > 0 {
+		if link, err := conn.FollowLink(sds.Slice()[0]); err != nil {
+			log.Printf("Error getting storage domain of disk '%s': %s", diskID, err)
+		} else if sd, ok := link.(*ovirtsdk4.StorageDomain); !ok {
+			log.Printf("Unexpected storage domain of disk '%s': %T", diskID, link)
+		} else if name, ok := sd.Name(); ok {
+			state.Put("storage_domain", name)
+		}
 	}
 
 	return multistep.ActionContinue
@@ -0,0 +1,143 @@
+package ovirt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+type stepWaitForIP struct {
+	Timeout    time.Duration
+	Preference string
+}
+
+// Run executes the Packer build step that waits for the guest agent to report
+// the IP address of the VM
+func (s *stepWaitForIP) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+	conn := state.Get("conn").(*ovirtsdk4.Connection)
+	vmID := state.Get("vm_id").(string)
+
+	ui.Say(fmt.Sprintf("Waiting for VM to report IP address (timeout %s) ...", s.Timeout))
+
+	listIPs := func() ([]*ovirtsdk4.Ip, error) {
+		resp, err := conn.SystemService().
+			VmsService().
+			VmService(vmID).
+			ReportedDevicesService().
+			List().
+			Send()
+		if err != nil {
+			return nil, fmt.Errorf("Error getting reported devices of VM: %s", err)
+		}
+
+		var ips []*ovirtsdk4.Ip
+		if devices, ok := resp.ReportedDevice(); ok {
+			for _, device := range devices.Slice() {
+				if deviceIPs, ok := device.Ips(); ok {
+					ips = append(ips, deviceIPs.Slice()...)
+				}
+			}
+		}
+		return ips, nil
+	}
+
+	ip, err := waitForIPAddress(ctx, listIPs, s.Preference, s.Timeout, 5*time.Second)
+	if err != nil {
+		err = fmt.Errorf("%s of VM: %s", err, vmID)
+		ui.Error(err.Error())
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("VM reported IP address: %s", ip))
+	state.Put("ip_address", ip)
+	return multistep.ActionContinue
+}
+
+// Cleanup any resources that may have been created during the Run phase.
+func (s *stepWaitForIP) Cleanup(state multistep.StateBag) {
+	// Nothing to cleanup for this step.
+}
+
+// waitForIPAddress polls the addresses returned by listIPs until one of the
+// preferred IP version is found. An address of the other version is only
+// used once the timeout has expired, as e.g. SLAAC may take longer than
+// DHCP.
+func waitForIPAddress(ctx context.Context, listIPs func() ([]*ovirtsdk4.Ip, error), preference string, timeout time.Duration, interval time.Duration) (string, error) {
+	deadline := time.After(timeout)
+	var fallback string
+	for {
+		ips, err := listIPs()
+		if err != nil {
+			return "", err
+		}
+
+		preferred, other := selectIPAddress(ips, preference)
+		if preferred != "" {
+			return preferred, nil
+		}
+		if other != "" {
+			fallback = other
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", errors.New("Interrupted while waiting for IP address")
+		case <-deadline:
+			if fallback != "" {
+				log.Printf("No %s address reported, using: %s", preference, fallback)
+				return fallback, nil
+			}
+			return "", errors.New("Timeout while waiting for IP address")
+		case <-time.After(interval):
+			log.Printf("No usable %s address reported yet", preference)
+		}
+	}
+}
+
+// selectIPAddress returns the first usable address of the preferred IP
+// version ("ipv4" or "ipv6") and the first usable address of the other
+// version. Loopback and link-local addresses are never returned.
+func selectIPAddress(ips []*ovirtsdk4.Ip, preference string) (string, string) {
+	preferredVersion := ovirtsdk4.IPVERSION_V4
+	if preference == "ipv6" {
+		preferredVersion = ovirtsdk4.IPVERSION_V6
+	}
+
+	var fallback string
+	for _, ip := range ips {
+		address, ok := ip.Address()
+		if !ok {
+			continue
+		}
+		parsed := net.ParseIP(address)
+		if parsed == nil || parsed.IsLoopback() || parsed.IsLinkLocalUnicast() {
+			continue
+		}
+
+		version, ok := ip.Version()
+		if !ok {
+			version = ovirtsdk4.IPVERSION_V6
+			if parsed.To4() != nil {
+				version = ovirtsdk4.IPVERSION_V4
+			}
+		}
+
+		if version == preferredVersion {
+			return address, fallback
+		}
+		if fallback == "" {
+			fallback = address
+		}
+	}
+
+	return "", fallback
+}
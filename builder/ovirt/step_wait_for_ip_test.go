@@ -0,0 +1,79 @@
+package ovirt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ovirtsdk4 "github.com/ovirt/go-ovirt"
+)
+
+func TestSelectIPAddress(t *testing.T) {
+	ips := []*ovirtsdk4.Ip{
+		testIP("127.0.0.1", ovirtsdk4.IPVERSION_V4),
+		testIP("fe80::21a:4aff:fe16:151", ovirtsdk4.IPVERSION_V6),
+		testIP("2001:db8::10", ovirtsdk4.IPVERSION_V6),
+		testIP("192.168.0.10", ovirtsdk4.IPVERSION_V4),
+	}
+
+	if result, _ := selectIPAddress(ips, "ipv4"); result != "192.168.0.10" {
+		t.Fatalf("wrong ipv4 address selected: %s", result)
+	}
+	if result, _ := selectIPAddress(ips, "ipv6"); result != "2001:db8::10" {
+		t.Fatalf("wrong ipv6 address selected: %s", result)
+	}
+	if result, fallback := selectIPAddress(ips[2:3], "ipv4"); (result != "") || (fallback != "2001:db8::10") {
+		t.Fatalf("should return other ip version as fallback: %s, %s", result, fallback)
+	}
+	if result, fallback := selectIPAddress(ips[:2], "ipv4"); (result != "") || (fallback != "") {
+		t.Fatalf("should not select loopback or link-local address: %s, %s", result, fallback)
+	}
+	if result, fallback := selectIPAddress(nil, "ipv4"); (result != "") || (fallback != "") {
+		t.Fatalf("should not select address from empty list: %s, %s", result, fallback)
+	}
+}
+
+func TestWaitForIPAddress(t *testing.T) {
+	// The IPv6 address is only reported after the IPv4 address
+	calls := 0
+	listIPs := func() ([]*ovirtsdk4.Ip, error) {
+		calls++
+		ips := []*ovirtsdk4.Ip{testIP("192.168.0.10", ovirtsdk4.IPVERSION_V4)}
+		if calls > 2 {
+			ips = append(ips, testIP("2001:db8::10", ovirtsdk4.IPVERSION_V6))
+		}
+		return ips, nil
+	}
+	result, err := waitForIPAddress(context.Background(), listIPs, "ipv6", time.Second, time.Millisecond)
+	if err != nil {
+		t.Fatalf("should not fail waiting for ipv6 address: %s", err)
+	}
+	if result != "2001:db8::10" {
+		t.Fatalf("should wait for preferred ip version: %s", result)
+	}
+
+	listIPs = func() ([]*ovirtsdk4.Ip, error) {
+		return []*ovirtsdk4.Ip{testIP("192.168.0.10", ovirtsdk4.IPVERSION_V4)}, nil
+	}
+	result, err = waitForIPAddress(context.Background(), listIPs, "ipv6", 50*time.Millisecond, time.Millisecond)
+	if err != nil {
+		t.Fatalf("should not fail if fallback address is available: %s", err)
+	}
+	if result != "192.168.0.10" {
+		t.Fatalf("should fall back to other ip version after timeout: %s", result)
+	}
+
+	listIPs = func() ([]*ovirtsdk4.Ip, error) {
+		return nil, nil
+	}
+	if _, err := waitForIPAddress(context.Background(), listIPs, "ipv4", 10*time.Millisecond, time.Millisecond); err == nil {
+		t.Fatal("should fail without any address after timeout")
+	}
+}
+
+func testIP(address string, version ovirtsdk4.IpVersion) *ovirtsdk4.Ip {
+	return ovirtsdk4.NewIpBuilder().
+		Address(address).
+		Version(version).
+		MustBuild()
+}
@@ -0,0 +1,14 @@
+package ovirt
+
+import (
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func winRMConfig(state multistep.StateBag) (*communicator.WinRMConfig, error) {
+	c := state.Get("config").(*Config)
+	return &communicator.WinRMConfig{
+		Username: c.Comm.WinRMUser,
+		Password: c.Comm.WinRMPassword,
+	}, nil
+}